@@ -0,0 +1,100 @@
+// Package bulk parsea archivos CSV o XLSX con columnas label,personas,gpp
+// para alimentar cálculos de discada en lote (ver /hx/calc/bulk y /api/calc/bulk).
+package bulk
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Row es una fila de entrada ya validada: personas/gpp para un cálculo,
+// identificado por Label. Line es el número de línea de origen (1-based,
+// contando el encabezado como línea 1) para poder reportar errores por fila.
+type Row struct {
+	Line     int
+	Label    string
+	Personas int
+	GPP      int
+}
+
+// RowError asocia un error de parseo o validación a su línea de origen.
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string { return fmt.Sprintf("línea %d: %s", e.Line, e.Message) }
+
+// ParseCSV lee un archivo CSV con encabezado label,personas,gpp (en cualquier orden).
+func ParseCSV(r io.Reader) ([]Row, []RowError) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, []RowError{{Message: fmt.Sprintf("leyendo CSV: %v", err)}}
+	}
+	return parseRecords(records)
+}
+
+// ParseXLSX lee la primera hoja de un archivo XLSX con encabezado label,personas,gpp.
+func ParseXLSX(r io.Reader) ([]Row, []RowError) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, []RowError{{Message: fmt.Sprintf("leyendo XLSX: %v", err)}}
+	}
+	defer f.Close()
+
+	records, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		return nil, []RowError{{Message: fmt.Sprintf("leyendo hoja: %v", err)}}
+	}
+	return parseRecords(records)
+}
+
+// parseRecords valida y convierte filas crudas (ya separadas en columnas) en Rows.
+func parseRecords(records [][]string) ([]Row, []RowError) {
+	if len(records) == 0 {
+		return nil, []RowError{{Message: "archivo vacío"}}
+	}
+
+	col := make(map[string]int, 3)
+	for i, name := range records[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"label", "personas", "gpp"} {
+		if _, ok := col[required]; !ok {
+			return nil, []RowError{{Message: fmt.Sprintf("falta la columna %q", required)}}
+		}
+	}
+
+	var rows []Row
+	var errs []RowError
+	for i, rec := range records[1:] {
+		line := i + 2 // +1 por 1-based, +1 por el encabezado
+		label := strings.TrimSpace(field(rec, col["label"]))
+		personas, perr := strconv.Atoi(strings.TrimSpace(field(rec, col["personas"])))
+		gpp, gerr := strconv.Atoi(strings.TrimSpace(field(rec, col["gpp"])))
+
+		switch {
+		case label == "":
+			errs = append(errs, RowError{Line: line, Message: "label vacío"})
+		case perr != nil || personas < 1 || personas > 1000:
+			errs = append(errs, RowError{Line: line, Message: "personas debe ser un entero entre 1 y 1000"})
+		case gerr != nil || gpp < 1:
+			errs = append(errs, RowError{Line: line, Message: "gpp debe ser un entero mayor o igual a 1"})
+		default:
+			rows = append(rows, Row{Line: line, Label: label, Personas: personas, GPP: gpp})
+		}
+	}
+	return rows, errs
+}
+
+func field(rec []string, i int) string {
+	if i < 0 || i >= len(rec) {
+		return ""
+	}
+	return rec[i]
+}
@@ -0,0 +1,137 @@
+// Package cart arma una lista de compras a partir de un cálculo de discada,
+// agrupada por retailer, y la exporta en texto plano, Markdown o CSV.
+package cart
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Item es una línea comprable: un ingrediente ya resuelto a un retailer y precio.
+type Item struct {
+	Ingredient   string
+	Retailer     string
+	URL          string
+	AddToCartURL string // deep link de "agregar al carrito", si el retailer lo soporta
+	Units        int
+	Subtotal     float64
+	IVA          float64
+	Total        float64
+}
+
+// Cart agrupa los Items por retailer y trae los totales generales.
+type Cart struct {
+	Currency   string
+	Items      []Item
+	ByRetailer map[string][]Item
+	Subtotal   float64
+	IVA        float64
+	Total      float64
+}
+
+// retailerAddToCartParam son los retailers que soportan un deep link de
+// "agregar al carrito" vía query param sobre la URL del producto, y el
+// parámetro que lo activa. Los demás retailers solo exponen la página del
+// producto, así que AddToCartURL queda vacío para ellos.
+var retailerAddToCartParam = map[string]string{
+	"walmart_mx":     "action=addtocart",
+	"bodega_aurrera": "action=addtocart",
+}
+
+// addToCartURLFor arma el deep link de "agregar al carrito" para un producto,
+// o "" si el retailer no lo soporta.
+func addToCartURLFor(retailer, productURL string) string {
+	param, ok := retailerAddToCartParam[retailer]
+	if !ok || productURL == "" {
+		return ""
+	}
+	sep := "?"
+	if strings.Contains(productURL, "?") {
+		sep = "&"
+	}
+	return productURL + sep + param
+}
+
+// Build arma un Cart a partir de la lista de items ya cotizados.
+func Build(currency string, items []Item) *Cart {
+	c := &Cart{Currency: currency, Items: items, ByRetailer: make(map[string][]Item)}
+	for i, it := range items {
+		if it.AddToCartURL == "" {
+			it.AddToCartURL = addToCartURLFor(it.Retailer, it.URL)
+			items[i] = it
+		}
+		c.ByRetailer[it.Retailer] = append(c.ByRetailer[it.Retailer], it)
+		c.Subtotal += it.Subtotal
+		c.IVA += it.IVA
+		c.Total += it.Total
+	}
+	return c
+}
+
+// retailersSorted devuelve los nombres de retailer del carrito en orden
+// alfabético, para que Text/Markdown salgan igual en cada request.
+func (c *Cart) retailersSorted() []string {
+	names := make([]string, 0, len(c.ByRetailer))
+	for r := range c.ByRetailer {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Text exporta el carrito como lista de texto plano, agrupada por retailer.
+func (c *Cart) Text() string {
+	var b strings.Builder
+	for _, retailer := range c.retailersSorted() {
+		fmt.Fprintf(&b, "== %s ==\n", retailer)
+		for _, it := range c.ByRetailer[retailer] {
+			fmt.Fprintf(&b, "- %s x%d: $%.2f %s (%s)", it.Ingredient, it.Units, it.Total, c.Currency, it.URL)
+			if it.AddToCartURL != "" {
+				fmt.Fprintf(&b, " [agregar al carrito: %s]", it.AddToCartURL)
+			}
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&b, "\nSubtotal: $%.2f %s\nIVA: $%.2f %s\nTotal: $%.2f %s\n",
+		c.Subtotal, c.Currency, c.IVA, c.Currency, c.Total, c.Currency)
+	return b.String()
+}
+
+// Markdown exporta el carrito como checklist de Markdown, agrupado por retailer.
+func (c *Cart) Markdown() string {
+	var b strings.Builder
+	for _, retailer := range c.retailersSorted() {
+		fmt.Fprintf(&b, "## %s\n\n", retailer)
+		for _, it := range c.ByRetailer[retailer] {
+			fmt.Fprintf(&b, "- [ ] [%s](%s) x%d — $%.2f %s", it.Ingredient, it.URL, it.Units, it.Total, c.Currency)
+			if it.AddToCartURL != "" {
+				fmt.Fprintf(&b, " ([agregar al carrito](%s))", it.AddToCartURL)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "**Subtotal:** $%.2f %s  \n**IVA:** $%.2f %s  \n**Total:** $%.2f %s\n",
+		c.Subtotal, c.Currency, c.IVA, c.Currency, c.Total, c.Currency)
+	return b.String()
+}
+
+// CSV exporta el carrito como CSV con columnas
+// ingrediente,url,add_to_cart_url,unidades,subtotal,iva,total.
+func (c *Cart) CSV() string {
+	var b strings.Builder
+	b.WriteString("ingrediente,url,add_to_cart_url,unidades,subtotal,iva,total\n")
+	for _, it := range c.Items {
+		fmt.Fprintf(&b, "%s,%s,%s,%d,%.2f,%.2f,%.2f\n",
+			csvEscape(it.Ingredient), csvEscape(it.URL), csvEscape(it.AddToCartURL), it.Units, it.Subtotal, it.IVA, it.Total)
+	}
+	return b.String()
+}
+
+func csvEscape(s string) string {
+	if strings.ContainsAny(s, ",\"\n") {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// seriesIDs mapea pares MXN/divisa a su serie del SIE de Banxico.
+// SF43718: tipo de cambio FIX USD/MXN. SF46410: tipo de cambio EUR/MXN.
+var seriesIDs = map[string]string{
+	"MXN_USD": "SF43718",
+	"MXN_EUR": "SF46410",
+}
+
+// BanxicoProvider obtiene el tipo de cambio vigente del API SIE de Banxico
+// (https://www.banxico.org.mx/SieAPIRest/).
+type BanxicoProvider struct {
+	Token      string
+	httpClient *http.Client
+}
+
+func NewBanxicoProvider(token string) *BanxicoProvider {
+	return &BanxicoProvider{Token: token, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *BanxicoProvider) Name() string { return "banxico" }
+
+type sieResponse struct {
+	Bmx struct {
+		Series []struct {
+			Datos []struct {
+				Dato string `json:"dato"`
+			} `json:"datos"`
+		} `json:"series"`
+	} `json:"bmx"`
+}
+
+func (p *BanxicoProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	series, ok := seriesIDs[from+"_"+to]
+	if !ok {
+		return 0, fmt.Errorf("par %s/%s no soportado por Banxico aquí", from, to)
+	}
+
+	url := fmt.Sprintf("https://www.banxico.org.mx/SieAPIRest/service/v1/series/%s/datos/oportuno", series)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("creando request: %w", err)
+	}
+	req.Header.Set("Bmx-Token", p.Token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("llamando Banxico: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Banxico status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("leyendo body: %w", err)
+	}
+
+	var sr sieResponse
+	if err := json.Unmarshal(body, &sr); err != nil {
+		return 0, fmt.Errorf("parseando respuesta de Banxico: %w", err)
+	}
+	if len(sr.Bmx.Series) == 0 || len(sr.Bmx.Series[0].Datos) == 0 {
+		return 0, fmt.Errorf("respuesta de Banxico sin datos para %s", series)
+	}
+
+	mxnPerUnit, err := strconv.ParseFloat(sr.Bmx.Series[0].Datos[0].Dato, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parseando dato de Banxico: %w", err)
+	}
+	if mxnPerUnit == 0 {
+		return 0, fmt.Errorf("tipo de cambio inválido (0) para %s", series)
+	}
+	// La serie reporta MXN por 1 unidad de `to`; invertimos para ir de MXN a `to`.
+	return 1 / mxnPerUnit, nil
+}
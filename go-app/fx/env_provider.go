@@ -0,0 +1,28 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// EnvProvider lee tipos de cambio fijos de variables de entorno, p.ej.
+// FX_RATE_MXN_USD=0.058. Útil para desarrollo y para cuando no hay acceso
+// a un proveedor en vivo.
+type EnvProvider struct{}
+
+func (EnvProvider) Name() string { return "env" }
+
+func (EnvProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	key := fmt.Sprintf("FX_RATE_%s_%s", from, to)
+	raw := os.Getenv(key)
+	if raw == "" {
+		return 0, fmt.Errorf("variable de entorno %s no definida", key)
+	}
+	rate, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parseando %s=%q: %w", key, raw, err)
+	}
+	return rate, nil
+}
@@ -0,0 +1,39 @@
+// Package fx convierte montos en MXN a otras divisas, a través de un
+// Provider intercambiable (variables de entorno o el API de Banxico).
+package fx
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Select construye el Provider configurado por la variable de entorno
+// FX_PROVIDER ("env" por defecto, o "banxico" con FX_BANXICO_TOKEN).
+func Select() Provider {
+	switch os.Getenv("FX_PROVIDER") {
+	case "banxico":
+		return NewBanxicoProvider(os.Getenv("FX_BANXICO_TOKEN"))
+	default:
+		return EnvProvider{}
+	}
+}
+
+// Provider obtiene el tipo de cambio entre dos divisas.
+type Provider interface {
+	Name() string
+	// Rate devuelve cuántas unidades de `to` equivalen a 1 unidad de `from`.
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// Convert usa el Provider dado para convertir `amount` de `from` a `to`.
+func Convert(ctx context.Context, p Provider, amount float64, from, to string) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+	rate, err := p.Rate(ctx, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("obteniendo tipo de cambio %s/%s (%s): %w", from, to, p.Name(), err)
+	}
+	return amount * rate, nil
+}
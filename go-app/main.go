@@ -1,114 +1,593 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
 	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
-	"regexp"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/Druidroid/Discada-Calculadora/go-app/bulk"
+	"github.com/Druidroid/Discada-Calculadora/go-app/cart"
+	"github.com/Druidroid/Discada-Calculadora/go-app/fx"
+	"github.com/Druidroid/Discada-Calculadora/go-app/middleware"
+	"github.com/Druidroid/Discada-Calculadora/go-app/pricestore"
+	"github.com/Druidroid/Discada-Calculadora/go-app/recipe"
+	"github.com/Druidroid/Discada-Calculadora/go-app/scrapers"
 )
 
+// priceStore persiste el historial de precios; nil si no se pudo abrir (la app
+// sigue funcionando, solo sin historial/sparklines/alertas).
+var priceStore *pricestore.Store
+
 // Timeout total por request (por ingrediente)
 const perReqTimeout = 60 * time.Second
 
 // Cache TTL de 5 minutos
 const cacheTTL = 5 * time.Minute
 
-var httpClient = &http.Client{Timeout: perReqTimeout}
-
 // -------------------- Datos de receta --------------------
 
-var (
-	// Total receta base (solo se usa para escalar bebidas)
-	totalBaseGrams float64 = 2937.5
-
-	// Ratios SOLO de proteínas (suman 1.0 dentro del bloque de proteínas)
-	proteinRatios = map[string]float64{
-		"Pulpa de res picada": 0.55,
-		"Tocino picado":       0.075,
-		"Jamon en cuadros":    0.175,
-		"Salchicha p/Asar":    0.125,
-		"Chorizo":             0.075,
-	}
-
-	// Cebolla: ratio separado (no influye en proteínas)
-	onionRatio float64 = 0.175
-
-	// Bebidas: cantidades base por tanda
-	baseUnits = map[string]float64{
-		"Cerveza":             3.125,
-		"Jugo de verduras V8": 1.0,
-	}
-
-	// URLs de scraping (Alsúper directo)
-	ingredientURLs = map[string]string{
-		"Pulpa de res picada": "https://alsuper.com/producto/pulpa-de-res-picada-357825",
-		"Tocino picado":       "https://alsuper.com/producto/tocineta-413218",
-		"Jamon en cuadros":    "https://alsuper.com/producto/jamon-de-pierna-horneado-428669",
-		"Salchicha p/Asar":    "https://alsuper.com/producto/salchicha-para-asar-238828",
-		"Chorizo":             "https://alsuper.com/producto/chorizo-319544",
-		"Cebolla blanca":      "https://alsuper.com/producto/cebolla-blanca-924",
-		"Cerveza":             "https://alsuper.com/producto/cerveza-six-pack-lata-323328",
-		"Jugo de verduras V8": "https://alsuper.com/producto/nectar-mixto-de-450697",
+// defaultRecipeName es la receta usada cuando el caller no pide ninguna en particular.
+const defaultRecipeName = "Clásica"
+
+// -------------------- IVA --------------------
+
+// defaultIVARate es la tasa general de IVA en México.
+const defaultIVARate = 0.16
+
+// ivaExemptItems son los ingredientes que, según el artículo 2-A de la LIVA
+// (alimentos de la canasta básica sin industrializar), están gravados a tasa 0%.
+var ivaExemptItems = map[string]bool{
+	"Pulpa de res picada": true,
+	"Cebolla blanca":      true,
+}
+
+// ivaRateFor devuelve la tasa de IVA aplicable a un ingrediente.
+func ivaRateFor(name string) float64 {
+	if ivaExemptItems[name] {
+		return 0
 	}
-)
+	return defaultIVARate
+}
 
-// -------------------- Modelos --------------------
+// -------------------- Tabla nutricional --------------------
+
+// NutritionFacts son los macros por 100 g (o 100 ml para bebidas) del ingrediente.
+type NutritionFacts struct {
+	Kcal      float64 `json:"kcal"`
+	ProteinG  float64 `json:"protein_g"`
+	CarbsG    float64 `json:"carbs_g"`
+	FatG      float64 `json:"fat_g"`
+	SatFatG   float64 `json:"sat_fat_g"`
+	FoodGroup string  `json:"food_group"`
+}
+
+// NutritionTable: valores de referencia por 100 g, tomados de tablas de composición
+// de alimentos al uso (SMAE / USDA), redondeados para uso orientativo, no clínico.
+var NutritionTable = map[string]NutritionFacts{
+	"Pulpa de res picada": {Kcal: 250, ProteinG: 26, CarbsG: 0, FatG: 16, SatFatG: 6.5, FoodGroup: "carnes"},
+	"Tocino picado":       {Kcal: 541, ProteinG: 37, CarbsG: 1.4, FatG: 42, SatFatG: 14, FoodGroup: "carnes"},
+	"Jamon en cuadros":    {Kcal: 145, ProteinG: 21, CarbsG: 1.5, FatG: 6, SatFatG: 2, FoodGroup: "carnes"},
+	"Salchicha p/Asar":    {Kcal: 300, ProteinG: 12, CarbsG: 3, FatG: 27, SatFatG: 9.5, FoodGroup: "carnes"},
+	"Chorizo":             {Kcal: 455, ProteinG: 24, CarbsG: 2, FatG: 38, SatFatG: 14, FoodGroup: "carnes"},
+	"Camarón pelado":      {Kcal: 99, ProteinG: 24, CarbsG: 0.2, FatG: 0.3, SatFatG: 0.1, FoodGroup: "mariscos"},
+	"Cebolla blanca":      {Kcal: 40, ProteinG: 1.1, CarbsG: 9.3, FatG: 0.1, SatFatG: 0, FoodGroup: "verduras"},
+	"Cerveza":             {Kcal: 43, ProteinG: 0.5, CarbsG: 3.6, FatG: 0, SatFatG: 0, FoodGroup: "bebidas"},
+	"Jugo de verduras V8": {Kcal: 20, ProteinG: 0.8, CarbsG: 4.3, FatG: 0.1, SatFatG: 0, FoodGroup: "bebidas"},
+}
+
+// NutritionTotals son los macros totales ya escalados a los gramos/ml comprados de un ingrediente.
+type NutritionTotals struct {
+	Kcal     float64 `json:"kcal" xml:"kcal" yaml:"kcal"`
+	ProteinG float64 `json:"protein_g" xml:"protein_g" yaml:"protein_g"`
+	CarbsG   float64 `json:"carbs_g" xml:"carbs_g" yaml:"carbs_g"`
+	FatG     float64 `json:"fat_g" xml:"fat_g" yaml:"fat_g"`
+	SatFatG  float64 `json:"sat_fat_g" xml:"sat_fat_g" yaml:"sat_fat_g"`
+}
 
-type scraperPrice struct {
-	URL        string   `json:"url"`
-	Product    *string  `json:"product_name,omitempty"`
-	PricePerKg *float64 `json:"price_per_kg,omitempty"` // para productos a granel
-	UnitPrice  *float64 `json:"unit_price,omitempty"`   // para pieza/paquete/lata/six
-	Currency   string   `json:"currency"`
+// FoodGroupShare es el porcentaje del VCT que aporta un grupo de alimentos.
+type FoodGroupShare struct {
+	Group      string  `json:"group" xml:"group" yaml:"group"`
+	Kcal       float64 `json:"kcal" xml:"kcal" yaml:"kcal"`
+	PercentVCT float64 `json:"percent_vct" xml:"percent_vct" yaml:"percent_vct"`
 }
 
+// NutritionSummary agrega los macros de toda la receta y el reparto por grupo de alimentos.
+type NutritionSummary struct {
+	Totals        NutritionTotals  `json:"totals" xml:"totals" yaml:"totals"`
+	VCT           float64          `json:"vct" xml:"vct" yaml:"vct"` // valor calórico total (Atwater 4/4/9)
+	KcalPerPerson float64          `json:"kcal_per_person" xml:"kcal_per_person" yaml:"kcal_per_person"`
+	FoodGroups    []FoodGroupShare `json:"food_groups" xml:"food_groups>group" yaml:"food_groups"`
+}
+
+// nutritionFor calcula los macros de un ingrediente dados los gramos realmente necesarios.
+func nutritionFor(name string, grams float64) NutritionTotals {
+	nf, ok := NutritionTable[name]
+	if !ok {
+		log.Printf("nutrición: %q no está en NutritionTable, se reporta en 0", name)
+		return NutritionTotals{}
+	}
+	scale := grams / 100.0
+	return NutritionTotals{
+		Kcal:     round2(nf.Kcal * scale),
+		ProteinG: round2(nf.ProteinG * scale),
+		CarbsG:   round2(nf.CarbsG * scale),
+		FatG:     round2(nf.FatG * scale),
+		SatFatG:  round2(nf.SatFatG * scale),
+	}
+}
+
+// summarizeNutrition agrega los macros de los items y reparte el VCT por grupo de alimentos.
+func summarizeNutrition(items []IngredientCalc, personas int) NutritionSummary {
+	var totals NutritionTotals
+	groupKcal := make(map[string]float64)
+
+	for _, it := range items {
+		nf, ok := NutritionTable[it.Name]
+		if !ok || it.Nutrition == nil {
+			continue
+		}
+		totals.Kcal += it.Nutrition.Kcal
+		totals.ProteinG += it.Nutrition.ProteinG
+		totals.CarbsG += it.Nutrition.CarbsG
+		totals.FatG += it.Nutrition.FatG
+		totals.SatFatG += it.Nutrition.SatFatG
+		groupKcal[nf.FoodGroup] += it.Nutrition.Kcal
+	}
+
+	// VCT con factores de Atwater 4/4/9 (proteína/carbos a 4 kcal/g, grasa a 9 kcal/g)
+	vct := totals.ProteinG*4 + totals.CarbsG*4 + totals.FatG*9
+
+	groupNames := make([]string, 0, len(groupKcal))
+	for g := range groupKcal {
+		groupNames = append(groupNames, g)
+	}
+	sort.Strings(groupNames)
+
+	groups := make([]FoodGroupShare, 0, len(groupKcal))
+	for _, g := range groupNames {
+		kcal := groupKcal[g]
+		pct := 0.0
+		if vct > 0 {
+			pct = round2(kcal / vct * 100)
+		}
+		groups = append(groups, FoodGroupShare{Group: g, Kcal: round2(kcal), PercentVCT: pct})
+	}
+
+	kcalPerPerson := 0.0
+	if personas > 0 {
+		kcalPerPerson = round2(vct / float64(personas))
+	}
+
+	totals.Kcal = round2(totals.Kcal)
+	totals.ProteinG = round2(totals.ProteinG)
+	totals.CarbsG = round2(totals.CarbsG)
+	totals.FatG = round2(totals.FatG)
+	totals.SatFatG = round2(totals.SatFatG)
+
+	return NutritionSummary{
+		Totals:        totals,
+		VCT:           round2(vct),
+		KcalPerPerson: kcalPerPerson,
+		FoodGroups:    groups,
+	}
+}
+
+// -------------------- Modelos --------------------
+
 type IngredientCalc struct {
-	Name           string  `json:"name"`
-	URL            string  `json:"url"`
-	GramsNeeded    float64 `json:"grams_needed"`
-	UnitsNeeded    int     `json:"units_needed"`    // piezas/latas requeridas (visual)
-	PurchasedUnits int     `json:"purchased_units"` // paquetes/piezas/six comprados
-	PricePerKg     float64 `json:"price_per_kg"`    // visible en UI si aplica
-	UnitPrice      float64 `json:"unit_price"`      // visible en UI si aplica
-	Cost           float64 `json:"cost"`
-	Currency       string  `json:"currency"`
+	Name           string  `json:"name" xml:"name" yaml:"name"`
+	Retailer       string  `json:"retailer" xml:"retailer" yaml:"retailer"`
+	URL            string  `json:"url" xml:"url" yaml:"url"`
+	GramsNeeded    float64 `json:"grams_needed" xml:"grams_needed" yaml:"grams_needed"`
+	UnitsNeeded    int     `json:"units_needed" xml:"units_needed" yaml:"units_needed"`          // piezas/latas requeridas (visual)
+	PurchasedUnits int     `json:"purchased_units" xml:"purchased_units" yaml:"purchased_units"` // paquetes/piezas/six comprados
+	PricePerKg     float64 `json:"price_per_kg" xml:"price_per_kg" yaml:"price_per_kg"`          // visible en UI si aplica
+	UnitPrice      float64 `json:"unit_price" xml:"unit_price" yaml:"unit_price"`                // visible en UI si aplica
+	Cost           float64 `json:"cost" xml:"cost" yaml:"cost"`                                  // subtotal antes de IVA
+	IVARate        float64 `json:"iva_rate" xml:"iva_rate" yaml:"iva_rate"`
+	IVA            float64 `json:"iva" xml:"iva" yaml:"iva"`
+	Total          float64 `json:"total" xml:"total" yaml:"total"` // Cost + IVA
+	Currency       string  `json:"currency" xml:"currency" yaml:"currency"`
+
+	Nutrition  *NutritionTotals `json:"nutrition,omitempty" xml:"nutrition,omitempty" yaml:"nutrition,omitempty"`          // macros totales de este ingrediente
+	Alternates []scrapers.Price `json:"alternates,omitempty" xml:"alternates>price,omitempty" yaml:"alternates,omitempty"` // precios de todos los retailers que respondieron
+	Sparkline  template.HTML    `json:"-" xml:"-" yaml:"-"`                                                                // SVG de los últimos 30 días de precio (si hay pricestore)
 }
 
 type CalcResponse struct {
-	Personas         int              `json:"personas"`
-	GramosPorPersona int              `json:"gramos_por_persona"`
-	TotalGramos      float64          `json:"total_grams"`
-	Items            []IngredientCalc `json:"items"`
-	TotalCosto       float64          `json:"total_cost"`
-	Currency         string           `json:"currency"`
+	Personas         int              `json:"personas" xml:"personas" yaml:"personas"`
+	GramosPorPersona int              `json:"gramos_por_persona" xml:"gramos_por_persona" yaml:"gramos_por_persona"`
+	TotalGramos      float64          `json:"total_grams" xml:"total_grams" yaml:"total_grams"`
+	Recipe           string           `json:"recipe" xml:"recipe" yaml:"recipe"`
+	Items            []IngredientCalc `json:"items" xml:"items>item" yaml:"items"`
+	TotalCosto       float64          `json:"total_cost" xml:"total_cost" yaml:"total_cost"` // alias de Subtotal, por compatibilidad
+	Subtotal         float64          `json:"subtotal" xml:"subtotal" yaml:"subtotal"`
+	IVA              float64          `json:"iva" xml:"iva" yaml:"iva"`
+	Total            float64          `json:"total" xml:"total" yaml:"total"`
+	Currency         string           `json:"currency" xml:"currency" yaml:"currency"`
+
+	Nutrition NutritionSummary `json:"nutrition" xml:"nutrition" yaml:"nutrition"`
+}
+
+// -------------------- Validación de /calc --------------------
+
+// CalcRequest es el payload tipado de /hx/calc y /api/calc. Las tags de
+// validación usan el validator de go-playground que Gin ya trae integrado
+// en su binding.
+type CalcRequest struct {
+	Personas int    `form:"personas" json:"personas" binding:"required,min=1,max=1000"`
+	GPP      int    `form:"gpp" json:"gpp" binding:"required,min=0"`
+	Recipe   string `form:"recipe" json:"recipe"`
+}
+
+// fieldLabel traduce el nombre de campo de Go a su clave form/json.
+var fieldLabel = map[string]string{
+	"Personas": "personas",
+	"GPP":      "gpp",
+}
+
+// validationLocales es el registro de mensajes de error por locale y campo,
+// para poder sumar "en" (u otros) más adelante sin tocar el resto del código.
+var validationLocales = map[string]map[string]map[string]string{
+	"es": {
+		"Personas": {
+			"required": "El número de personas es obligatorio",
+			"min":      "Debe ser al menos 1 persona",
+			"max":      "No puede ser más de 1000 personas",
+		},
+		"GPP": {
+			"required": "Los gramos por persona son obligatorios",
+			"min":      "Los gramos por persona no pueden ser negativos",
+		},
+	},
+}
+
+// translateValidationErrors convierte los errores de validación de Gin/validator
+// en un mapa campo→mensaje en el locale pedido ("es" si no se reconoce).
+func translateValidationErrors(err error, locale string) map[string]string {
+	verrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return map[string]string{"_": err.Error()}
+	}
+	messages, ok := validationLocales[locale]
+	if !ok {
+		messages = validationLocales["es"]
+	}
+	out := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		key := fieldLabel[fe.Field()]
+		if key == "" {
+			key = strings.ToLower(fe.Field())
+		}
+		msg := messages[fe.Field()][fe.Tag()]
+		if msg == "" {
+			msg = fmt.Sprintf("%s no es válido", key)
+		}
+		out[key] = msg
+	}
+	return out
+}
+
+// -------------------- Exportación multi-formato de /calc --------------------
+
+// calcExportHandler resuelve un CalcRequest y renderiza el resultado en el
+// formato pedido por Accept, ?format= o la extensión de /api/calc.:ext.
+func calcExportHandler(c *gin.Context) {
+	var req CalcRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"errors": translateValidationErrors(err, "es")})
+		return
+	}
+	res, err := calcFor(req.Personas, req.GPP, req.Recipe)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	renderCalcExport(c, res, resolveExportFormat(c))
+}
+
+// acceptFormats mapea media types exactos a su formato de exportación. Si dos
+// tokens del Accept empatan en calidad (q), gana el que aparece primero en el Accept.
+var acceptFormats = []struct {
+	mediaType string
+	format    string
+}{
+	{"application/xml", "xml"},
+	{"text/xml", "xml"},
+	{"application/yaml", "yaml"},
+	{"application/x-yaml", "yaml"},
+	{"text/yaml", "yaml"},
+	{"text/csv", "csv"},
+	{"application/pdf", "pdf"},
+	{"application/json", "json"},
+}
+
+// resolveExportFormat decide el formato de salida: la extensión de ruta
+// (/api/calc.:ext) gana, luego ?format=, y por último negociación real del
+// header Accept (tokens exactos por calidad, no substring matching: un
+// Accept de navegador como "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+// no debe disparar XML solo porque la palabra aparece ahí adentro).
+func resolveExportFormat(c *gin.Context) string {
+	if ext := c.Param("ext"); ext != "" {
+		return ext
+	}
+	if q := c.Query("format"); q != "" {
+		return q
+	}
+	if format, ok := negotiateAccept(c.GetHeader("Accept")); ok {
+		return format
+	}
+	return "json"
+}
+
+// negotiateAccept elige, entre los media types soportados, el de mayor
+// calidad (q) declarado en el header Accept. Solo gana si su calidad supera
+// la de text/html: un navegador manda text/html primero (q=1.0 implícito) y
+// application/xml más abajo con q=0.9 "por si acaso" — eso es navegación
+// normal, no un pedido explícito de XML, así que cae al default json. Un
+// cliente que sí prefiere XML sobre HTML (o que no manda text/html) sigue
+// recibiendo XML.
+func negotiateAccept(accept string) (string, bool) {
+	htmlQ := -1.0
+	best := ""
+	bestQ := -1.0
+	for _, token := range strings.Split(accept, ",") {
+		mediaType, q := parseAcceptToken(token)
+		if mediaType == "text/html" && q > htmlQ {
+			htmlQ = q
+		}
+		for _, af := range acceptFormats {
+			if mediaType == af.mediaType && q > bestQ {
+				bestQ = q
+				best = af.format
+			}
+		}
+	}
+	if bestQ <= htmlQ {
+		return "", false
+	}
+	return best, best != ""
+}
+
+// parseAcceptToken separa un elemento de Accept ("application/xml;q=0.9") en
+// su media type (sin espacios) y su calidad (1.0 si no trae q=).
+func parseAcceptToken(token string) (mediaType string, q float64) {
+	q = 1.0
+	parts := strings.Split(token, ";")
+	mediaType = strings.TrimSpace(parts[0])
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+		if val, ok := strings.CutPrefix(param, "q="); ok {
+			if parsed, err := strconv.ParseFloat(val, 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return mediaType, q
+}
+
+// renderCalcExport escribe `res` en la respuesta según `format`.
+func renderCalcExport(c *gin.Context, res *CalcResponse, format string) {
+	switch format {
+	case "xml":
+		c.XML(http.StatusOK, res)
+	case "yaml", "yml":
+		c.YAML(http.StatusOK, res)
+	case "csv":
+		streamCalcCSV(c, res)
+	case "pdf":
+		renderCalcPDF(c, res)
+	default:
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// streamCalcCSV escribe, renglón por renglón según va generándolos, el costo
+// por persona del cálculo (persona, costo, moneda).
+func streamCalcCSV(c *gin.Context, res *CalcResponse) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="calculo.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"persona", "costo", "moneda"})
+	w.Flush()
+
+	costoPorPersona := round2(res.Total / float64(res.Personas))
+	for i := 1; i <= res.Personas; i++ {
+		w.Write([]string{strconv.Itoa(i), strconv.FormatFloat(costoPorPersona, 'f', 2, 64), res.Currency})
+		w.Flush()
+	}
+}
+
+// renderCalcPDF arma un PDF con los ingredientes de tableTpl, usando gofpdf
+// (puro Go, sin dependencias del sistema). Cada renglón trae nombre, gramos y
+// costo antes de IVA, y al final va el total con IVA; no incluye las columnas
+// de unidades, precio por kg, precio unitario ni tendencia, ni el subtotal y
+// el IVA por separado.
+func renderCalcPDF(c *gin.Context, res *CalcResponse) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+
+	pdf.SetFont("Arial", "B", 14)
+	pdf.CellFormat(0, 10, fmt.Sprintf("Discada para %d personas", res.Personas), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(90, 8, "Ingrediente", "1", 0, "", false, 0, "")
+	pdf.CellFormat(40, 8, "Gramos", "1", 0, "", false, 0, "")
+	pdf.CellFormat(40, 8, "Costo", "1", 1, "", false, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	for _, it := range res.Items {
+		pdf.CellFormat(90, 8, it.Name, "1", 0, "", false, 0, "")
+		pdf.CellFormat(40, 8, fmt.Sprintf("%.0f g", it.GramsNeeded), "1", 0, "", false, 0, "")
+		pdf.CellFormat(40, 8, fmt.Sprintf("$%.2f", it.Cost), "1", 1, "", false, 0, "")
+	}
+
+	pdf.SetFont("Arial", "B", 10)
+	pdf.CellFormat(130, 8, "Total", "1", 0, "", false, 0, "")
+	pdf.CellFormat(40, 8, fmt.Sprintf("$%.2f %s", res.Total, res.Currency), "1", 1, "", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="calculo.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// -------------------- Cálculo en lote (CSV/XLSX) --------------------
+
+// bulkMaxUploadBytes acota el tamaño del archivo subido a /calc/bulk.
+var bulkMaxUploadBytes = mustEnvInt64("BULK_MAX_UPLOAD_BYTES", 2*1024*1024)
+
+// bulkUploadRequest es el payload multipart de /hx/calc/bulk y /api/calc/bulk.
+type bulkUploadRequest struct {
+	File   *multipart.FileHeader `form:"file" binding:"required"`
+	Recipe string                `form:"recipe"`
+}
+
+// parseBulkUpload detecta CSV vs XLSX por la extensión del archivo y lo parsea
+// con el paquete bulk.
+func parseBulkUpload(fh *multipart.FileHeader) ([]bulk.Row, []bulk.RowError, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("abriendo archivo: %w", err)
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(strings.ToLower(fh.Filename), ".xlsx") {
+		rows, errs := bulk.ParseXLSX(f)
+		return rows, errs, nil
+	}
+	rows, errs := bulk.ParseCSV(f)
+	return rows, errs, nil
+}
+
+// -------------------- Config runtime ajustable por /admin --------------------
+
+var (
+	runtimeMu              sync.RWMutex
+	defaultGPPRuntime      = 250
+	defaultCurrencyRuntime = mustEnv("CURRENCY", "MXN")
+)
+
+func getDefaultGPP() int {
+	runtimeMu.RLock()
+	defer runtimeMu.RUnlock()
+	return defaultGPPRuntime
+}
+
+func getDefaultCurrency() string {
+	runtimeMu.RLock()
+	defer runtimeMu.RUnlock()
+	return defaultCurrencyRuntime
+}
+
+func setDefaultGPP(v int) {
+	runtimeMu.Lock()
+	defaultGPPRuntime = v
+	runtimeMu.Unlock()
+}
+
+func setDefaultCurrency(v string) {
+	runtimeMu.Lock()
+	defaultCurrencyRuntime = v
+	runtimeMu.Unlock()
+}
+
+// -------------------- Historial de cálculos (para /admin) --------------------
+
+// calcHistoryLimit acota cuántos cálculos recientes se guardan en memoria.
+const calcHistoryLimit = 50
+
+type calcHistoryEntry struct {
+	At       time.Time `json:"at"`
+	Personas int       `json:"personas"`
+	GPP      int       `json:"gpp"`
+	Recipe   string    `json:"recipe"`
+	Total    float64   `json:"total"`
+	Currency string    `json:"currency"`
+}
+
+var (
+	calcHistoryMu sync.Mutex
+	calcHistory   []calcHistoryEntry
+)
+
+// recordCalcHistory guarda un cálculo exitoso para mostrarlo en /admin,
+// recortando al límite más antiguo primero.
+func recordCalcHistory(res *CalcResponse) {
+	calcHistoryMu.Lock()
+	defer calcHistoryMu.Unlock()
+	calcHistory = append(calcHistory, calcHistoryEntry{
+		At:       time.Now(),
+		Personas: res.Personas,
+		GPP:      res.GramosPorPersona,
+		Recipe:   res.Recipe,
+		Total:    res.Total,
+		Currency: res.Currency,
+	})
+	if len(calcHistory) > calcHistoryLimit {
+		calcHistory = calcHistory[len(calcHistory)-calcHistoryLimit:]
+	}
+}
+
+// recentCalcHistory devuelve el historial guardado, del más reciente al más antiguo.
+func recentCalcHistory() []calcHistoryEntry {
+	calcHistoryMu.Lock()
+	defer calcHistoryMu.Unlock()
+	out := make([]calcHistoryEntry, len(calcHistory))
+	for i, e := range calcHistory {
+		out[len(calcHistory)-1-i] = e
+	}
+	return out
 }
 
 // -------------------- Cache simple --------------------
 
+// Clave de cache: "<scraper>|<url>", para que dos retailers nunca colisionen
+// aunque (en teoría) compartieran URL.
 type priceEntry struct {
 	at   time.Time
-	data *scraperPrice
+	data *scrapers.Price
 }
 
 var (
-	priceCache = make(map[string]priceEntry) // key: URL de Alsúper
+	priceCache = make(map[string]priceEntry)
 	cacheMu    sync.RWMutex
 )
 
-func cacheGet(url string) (*scraperPrice, bool) {
+func cacheKey(scraperName, url string) string {
+	return scraperName + "|" + url
+}
+
+func cacheGet(scraperName, url string) (*scrapers.Price, bool) {
 	cacheMu.RLock()
-	ent, ok := priceCache[url]
+	ent, ok := priceCache[cacheKey(scraperName, url)]
 	cacheMu.RUnlock()
 	if !ok {
 		return nil, false
@@ -119,12 +598,233 @@ func cacheGet(url string) (*scraperPrice, bool) {
 	return ent.data, true
 }
 
-func cacheSet(url string, pr *scraperPrice) {
+func cacheSet(scraperName, url string, pr *scrapers.Price) {
 	cacheMu.Lock()
-	priceCache[url] = priceEntry{at: time.Now(), data: pr}
+	priceCache[cacheKey(scraperName, url)] = priceEntry{at: time.Now(), data: pr}
 	cacheMu.Unlock()
 }
 
+// -------------------- Alertas de precio --------------------
+
+// alertRequest es el cuerpo esperado en POST /api/alerts.
+type alertRequest struct {
+	URL            string  `form:"url" json:"url" binding:"required"`
+	ThresholdPct   float64 `form:"threshold_pct" json:"threshold_pct" binding:"required"`
+	EmailOrWebhook string  `form:"email_or_webhook" json:"email_or_webhook" binding:"required"`
+}
+
+var (
+	alertsMu sync.Mutex
+	alerts   []alertRequest
+)
+
+// maxStoredAlerts acota cuántas alertas se guardan en memoria: /api/alerts no
+// requiere auth, así que sin un tope es un vector de crecimiento de memoria
+// sin límite. Al llegar al tope se descarta la alerta más vieja en vez de
+// rechazar la nueva, para que llenar el store no deje el endpoint
+// permanentemente inutilizable.
+const maxStoredAlerts = 1000
+
+func registerAlert(req alertRequest) {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	if len(alerts) >= maxStoredAlerts {
+		// Recorre el slice en vez de re-slicear desde el índice 1: eso
+		// conservaría la capacidad reducida y forzaría una realocación en
+		// cada append siguiente.
+		copy(alerts, alerts[1:])
+		alerts = alerts[:len(alerts)-1]
+	}
+	alerts = append(alerts, req)
+}
+
+// webhookDNSTimeout acota cuánto puede tardar la resolución de DNS en
+// isSafeWebhookURL; sin esto, un host cuyo nameserver no responde cuelga la
+// request de POST /api/alerts (que no tiene auth) indefinidamente.
+const webhookDNSTimeout = 3 * time.Second
+
+// isSafeWebhookURL valida que `raw` sea una URL http(s) cuyo host no resuelva
+// a una red privada, loopback o link-local (SSRF: sin esto, cualquier
+// anónimo podría registrar una alerta que apunte al endpoint de metadata de
+// la nube o a un servicio interno, y el checker la llamaría cada hora).
+func isSafeWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookDNSTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if isPrivateOrLocalIP(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func isPrivateOrLocalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// webhookHTTPClient es el cliente usado para notificar webhooks de alertas.
+// No sigue redirects (un host público podría responder 302 a una IP
+// privada/de metadata para esquivar isSafeWebhookURL) y resuelve el destino
+// él mismo en cada conexión, rechazando IPs privadas/locales en el momento
+// exacto de conectar — así no importa si el DNS cambió entre el chequeo y
+// el disparo del webhook (DNS rebinding).
+var webhookHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+	Transport: &http.Transport{DialContext: dialPublicOnly},
+}
+
+// dialPublicOnly resuelve `addr` y rechaza conectar a cualquier IP privada,
+// loopback o link-local, en vez de confiar en una validación hecha antes.
+func dialPublicOnly(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if isPrivateOrLocalIP(ip) {
+			return nil, fmt.Errorf("dialPublicOnly: %s resuelve a una red privada/local (%s)", host, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("dialPublicOnly: %s no resolvió a ninguna IP", host)
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].String(), port))
+}
+
+// alertCheckInterval es cada cuánto se vuelve a scrapear las URLs con alertas registradas.
+const alertCheckInterval = time.Hour
+
+// runAlertChecker corre en segundo plano, re-scrapeando las URLs con alertas
+// registradas y disparando el webhook cuando el precio se mueve más del umbral.
+func runAlertChecker(ctx context.Context) {
+	ticker := time.NewTicker(alertCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkAlerts(ctx)
+		}
+	}
+}
+
+func checkAlerts(ctx context.Context) {
+	alertsMu.Lock()
+	snapshot := make([]alertRequest, len(alerts))
+	copy(snapshot, alerts)
+	alertsMu.Unlock()
+
+	for _, a := range snapshot {
+		s := scrapers.For(a.URL)
+		if s == nil {
+			continue
+		}
+		fetchCtx, cancel := context.WithTimeout(ctx, perReqTimeout)
+		pr, err := s.Fetch(fetchCtx, a.URL)
+		cancel()
+		if err != nil {
+			log.Printf("alerts: error scrapeando %s: %v", a.URL, err)
+			continue
+		}
+		recordPriceHistory(pr)
+
+		if priceStore == nil {
+			continue
+		}
+		changePct, err := priceStore.PercentChange(a.URL, 7*24*time.Hour)
+		if err != nil {
+			log.Printf("alerts: error calculando cambio de %s: %v", a.URL, err)
+			continue
+		}
+		if math.Abs(changePct) >= a.ThresholdPct {
+			fireWebhook(a, changePct)
+		}
+	}
+}
+
+// fireWebhook notifica el movimiento de precio al destino registrado. Si no
+// parece una URL, solo se deja constancia en el log (el envío por correo no
+// está implementado).
+func fireWebhook(a alertRequest, changePct float64) {
+	if !strings.HasPrefix(a.EmailOrWebhook, "http://") && !strings.HasPrefix(a.EmailOrWebhook, "https://") {
+		log.Printf("alerts: %s cambió %.1f%%, notificar a %s (correo, no implementado)", a.URL, changePct, a.EmailOrWebhook)
+		return
+	}
+	// Se revalida el host al momento de disparar, no solo al registrar la
+	// alerta: el DNS pudo haber cambiado desde entonces (DNS rebinding).
+	if !isSafeWebhookURL(a.EmailOrWebhook) {
+		log.Printf("alerts: webhook %s ya no resuelve a un host público, no se llama", a.EmailOrWebhook)
+		return
+	}
+	payload := fmt.Sprintf(`{"url":%q,"change_pct":%.2f}`, a.URL, changePct)
+	req, err := http.NewRequest(http.MethodPost, a.EmailOrWebhook, strings.NewReader(payload))
+	if err != nil {
+		log.Printf("alerts: error creando webhook para %s: %v", a.URL, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		log.Printf("alerts: error llamando webhook %s: %v", a.EmailOrWebhook, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// -------------------- Rate limiting por scraper --------------------
+
+// scraperLimiter espacía las peticiones a un mismo retailer para no abusar de su sitio.
+type scraperLimiter struct {
+	mu       sync.Mutex
+	lastAt   map[string]time.Time
+	interval time.Duration
+}
+
+var rateLimiter = &scraperLimiter{lastAt: make(map[string]time.Time), interval: 500 * time.Millisecond}
+
+// wait bloquea hasta que haya pasado el intervalo mínimo desde la última
+// petición a ese scraper, o hasta que ctx se cancele.
+func (l *scraperLimiter) wait(ctx context.Context, scraperName string) error {
+	l.mu.Lock()
+	last, ok := l.lastAt[scraperName]
+	wait := time.Duration(0)
+	if ok {
+		if elapsed := time.Since(last); elapsed < l.interval {
+			wait = l.interval - elapsed
+		}
+	}
+	l.lastAt[scraperName] = time.Now().Add(wait)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // -------------------- Utilidades --------------------
 
 func mustEnv(key, fallback string) string {
@@ -135,6 +835,20 @@ func mustEnv(key, fallback string) string {
 	return v
 }
 
+// mustEnvInt64 es como mustEnv pero parseando el valor como entero; si la
+// variable no está definida o no es un entero válido, devuelve `fallback`.
+func mustEnvInt64(key string, fallback int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
 func ceilDiv(n, d int) int {
 	if n <= 0 {
 		return 0
@@ -150,290 +864,425 @@ func round2(x float64) float64 {
 	return math.Round(x*100) / 100
 }
 
-var priceRe = regexp.MustCompile(`\$[\s]*([0-9]+(?:\.[0-9]+)?)`)
-
-// extrae el precio del HTML de Alsúper usando las clases de precio
-func extractPriceFromHTML(html string) (float64, error) {
-	segment := html
+// fetchOne obtiene el precio de una sola URL a través del scraper que la reconoce,
+// aplicando cache y rate limiting por scraper.
+func fetchOne(ctx context.Context, name, url string) (*scrapers.Price, error) {
+	s := scrapers.For(url)
+	if s == nil {
+		return nil, fmt.Errorf("%s: no hay scraper registrado para %s", name, url)
+	}
 
-	// Intentar centrar el contexto en los spans de precio
-	if idx := strings.Index(html, "as-discount-price"); idx != -1 {
-		start := idx - 200
-		if start < 0 {
-			start = 0
-		}
-		end := idx + 200
-		if end > len(html) {
-			end = len(html)
-		}
-		segment = html[start:end]
-	} else if idx := strings.Index(html, "as-product-price"); idx != -1 {
-		start := idx - 200
-		if start < 0 {
-			start = 0
-		}
-		end := idx + 200
-		if end > len(html) {
-			end = len(html)
-		}
-		segment = html[start:end]
+	if pr, ok := cacheGet(s.Name(), url); ok {
+		return pr, nil
 	}
 
-	m := priceRe.FindStringSubmatch(segment)
-	if m == nil {
-		// fallback: buscar en todo el documento
-		m = priceRe.FindStringSubmatch(html)
-		if m == nil {
-			return 0, fmt.Errorf("no se encontró un precio con formato $123.45")
-		}
+	if err := rateLimiter.wait(ctx, s.Name()); err != nil {
+		return nil, err
 	}
 
-	raw := strings.ReplaceAll(m[1], ",", "")
-	val, err := strconv.ParseFloat(raw, 64)
+	pr, err := s.Fetch(ctx, url)
 	if err != nil {
-		return 0, fmt.Errorf("parseando precio %q: %w", raw, err)
+		return nil, fmt.Errorf("%s (%s): %w", name, s.Name(), err)
 	}
-	return val, nil
+
+	cacheSet(s.Name(), url, pr)
+	recordPriceHistory(pr)
+	return pr, nil
 }
 
-// Llamada directa a Alsúper (SIN microservicio Python)
-func fetchPrice(ctx context.Context, url string) (*scraperPrice, error) {
-	// cache por URL de producto
-	if pr, ok := cacheGet(url); ok {
-		return pr, nil
+// recordPriceHistory persiste una observación de precio si hay un pricestore configurado.
+func recordPriceHistory(pr *scrapers.Price) {
+	if priceStore == nil {
+		return
+	}
+	pricePerKg, unitPrice := 0.0, 0.0
+	if pr.PricePerKg != nil {
+		pricePerKg = *pr.PricePerKg
+	}
+	if pr.UnitPrice != nil {
+		unitPrice = *pr.UnitPrice
+	}
+	if err := priceStore.Record(pr.URL, time.Now(), pricePerKg, unitPrice, pr.Currency); err != nil {
+		log.Printf("pricestore: error guardando historial de %s: %v", pr.URL, err)
 	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creando request: %w", err)
+// priceAmount devuelve el valor comparable de un Price (precio por kg o unitario).
+func priceAmount(pr *scrapers.Price) (float64, bool) {
+	if pr.PricePerKg != nil {
+		return *pr.PricePerKg, true
+	}
+	if pr.UnitPrice != nil {
+		return *pr.UnitPrice, true
 	}
+	return 0, false
+}
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("llamando alsuper: %w", err)
+// fetchCheapest consulta en paralelo todas las URLs candidatas de un ingrediente,
+// con reintentos suaves por URL, y devuelve el precio más barato junto con los
+// precios de todos los retailers que respondieron (incluido el más barato)
+// para poder mostrar la comparación.
+func fetchCheapest(ctx context.Context, name string, urls []string) (*scrapers.Price, []scrapers.Price, error) {
+	type result struct {
+		pr  *scrapers.Price
+		err error
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("alsuper status %d", resp.StatusCode)
+	results := make([]result, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			var lastErr error
+			for attempt := 0; attempt < 3; attempt++ {
+				attemptCtx, cancel := context.WithTimeout(ctx, perReqTimeout)
+				pr, err := fetchOne(attemptCtx, name, url)
+				cancel()
+				if err == nil {
+					results[i] = result{pr: pr}
+					return
+				}
+				lastErr = err
+				time.Sleep(800 * time.Millisecond * time.Duration(attempt+1))
+			}
+			results[i] = result{err: lastErr}
+		}(i, url)
 	}
+	wg.Wait()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("leyendo body: %w", err)
+	var best *scrapers.Price
+	var bestAmount float64
+	alternates := make([]scrapers.Price, 0, len(urls))
+	var lastErr error
+	for _, r := range results {
+		if r.err != nil {
+			lastErr = r.err
+			continue
+		}
+		alternates = append(alternates, *r.pr)
+		amount, ok := priceAmount(r.pr)
+		if !ok {
+			continue
+		}
+		if best == nil || amount < bestAmount {
+			best = r.pr
+			bestAmount = amount
+		}
+	}
+
+	if best == nil {
+		return nil, nil, fmt.Errorf("%s: ningún retailer respondió: %w", name, lastErr)
 	}
+	return best, alternates, nil
+}
+
+// -------------------- Cálculo --------------------
 
-	price, err := extractPriceFromHTML(string(body))
+// fetchItemPrice cotiza un Item de receta en todas sus URLs candidatas; la
+// normalización según su UnitType la hace calcItem.
+func fetchItemPrice(it recipe.Item) (pr *scrapers.Price, alternates []scrapers.Price, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), perReqTimeout)
+	defer cancel()
+	return fetchCheapest(ctx, it.Name, it.URLs)
+}
+
+// calcItem resuelve gramos, empaque y costo de un Item según su UnitType
+// ("kg" o "pack") y el precio cotizado.
+func calcItem(it recipe.Item, gramsNeeded float64) (IngredientCalc, error) {
+	pr, alternates, err := fetchItemPrice(it)
 	if err != nil {
-		return nil, err
+		return IngredientCalc{}, err
 	}
 
-	// Determinar si es precio por Kg o por paquete según el ingrediente
-	name := ""
-	for n, u := range ingredientURLs {
-		if u == url {
-			name = n
-			break
-		}
+	unitPrice := 0.0
+	if pr.UnitPrice != nil {
+		unitPrice = *pr.UnitPrice
+	}
+	pricePerKg := 0.0
+	if pr.PricePerKg != nil {
+		pricePerKg = *pr.PricePerKg
 	}
 
-	pr := &scraperPrice{
-		URL:      url,
-		Currency: "MXN",
+	out := IngredientCalc{
+		Name:        it.Name,
+		Retailer:    pr.Scraper,
+		URL:         pr.URL,
+		Currency:    pr.Currency,
+		GramsNeeded: gramsNeeded,
+		Alternates:  alternates,
 	}
 
-	switch name {
-	case "Pulpa de res picada", "Tocino picado", "Jamon en cuadros", "Cebolla blanca":
-		pr.PricePerKg = &price
-	default:
-		pr.UnitPrice = &price
+	switch it.UnitType {
+	case "pack":
+		packSize := it.PackSizeG
+		if packSize <= 0 {
+			packSize = 1
+		}
+		packs := ceilDiv(int(math.Round(gramsNeeded)), int(packSize))
+		out.PurchasedUnits = packs
+
+		if unitPrice <= 0 && pricePerKg > 0 {
+			unitPrice = pricePerKg
+		}
+		out.UnitPrice = unitPrice
+		out.Cost = round2(float64(packs) * unitPrice)
+
+	default: // "kg"
+		if pricePerKg <= 0 && unitPrice > 0 {
+			pricePerKg = unitPrice
+		}
+		out.PricePerKg = pricePerKg
+		out.Cost = round2(gramsNeeded / 1000.0 * pricePerKg)
+
+		if it.PackSizeG > 0 {
+			out.UnitsNeeded = ceilDiv(int(math.Round(gramsNeeded)), int(it.PackSizeG))
+		}
 	}
 
-	cacheSet(url, pr)
-	return pr, nil
+	return out, nil
 }
 
-// Reintentos con backoff suave
-func fetchWithRetry(ctx context.Context, url string, attempts int, baseDelay time.Duration) (*scraperPrice, error) {
-	var lastErr error
-	for i := 0; i < attempts; i++ {
-		attemptCtx, cancel := context.WithTimeout(ctx, perReqTimeout)
-		pr, err := fetchPrice(attemptCtx, url)
-		cancel()
-		if err == nil {
-			return pr, nil
+// calcBeverage escala una bebida según la proporción de la receta actual
+// respecto a BaseTotalGrams, y la empaca en PackSize unidades (six-pack, etc).
+func calcBeverage(bv recipe.Beverage, scale float64) (IngredientCalc, error) {
+	pr, alternates, err := func() (*scrapers.Price, []scrapers.Price, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), perReqTimeout)
+		defer cancel()
+		return fetchCheapest(ctx, bv.Name, bv.URLs)
+	}()
+	if err != nil {
+		return IngredientCalc{}, err
+	}
+
+	unitPrice := 0.0
+	if pr.UnitPrice != nil {
+		unitPrice = *pr.UnitPrice
+	}
+	if unitPrice <= 0 && pr.PricePerKg != nil {
+		unitPrice = *pr.PricePerKg
+	}
+
+	unitsNeeded := int(math.Ceil(scale * bv.BaseUnits))
+	packSize := bv.PackSize
+	if packSize <= 0 {
+		packSize = 1
+	}
+	purchased := 0
+	if unitsNeeded > 0 {
+		purchased = int(math.Ceil(float64(unitsNeeded) / float64(packSize)))
+		if purchased < 1 {
+			purchased = 1
 		}
-		lastErr = err
-		time.Sleep(baseDelay * time.Duration(i+1))
 	}
-	return nil, fmt.Errorf("fetchWithRetry: %w", lastErr)
+
+	return IngredientCalc{
+		Name:           bv.Name,
+		Retailer:       pr.Scraper,
+		URL:            pr.URL,
+		Currency:       pr.Currency,
+		GramsNeeded:    float64(unitsNeeded) * bv.UnitSizeMl,
+		UnitsNeeded:    unitsNeeded,
+		PurchasedUnits: purchased,
+		UnitPrice:      unitPrice,
+		Cost:           round2(float64(purchased) * unitPrice),
+		Alternates:     alternates,
+	}, nil
 }
 
-// -------------------- Cálculo --------------------
+// recipeItemURLs busca, en todas las recetas registradas, las URLs candidatas
+// de un ingrediente o bebida por nombre. Usado por /hx/compare.
+func recipeItemURLs(name string) ([]string, bool) {
+	for _, r := range recipe.List() {
+		for _, g := range r.Groups {
+			for _, it := range g.Items {
+				if it.Name == name {
+					return it.URLs, true
+				}
+			}
+		}
+		for _, bv := range r.Beverages {
+			if bv.Name == name {
+				return bv.URLs, true
+			}
+		}
+	}
+	return nil, false
+}
 
-// Mantiene toda la lógica de proporciones que ya acordamos
-func calcFor(personas, gpp int) (*CalcResponse, error) {
+// calcFor calcula el costo y los macros de la receta `recipeName` para N personas.
+func calcFor(personas, gpp int, recipeName string) (*CalcResponse, error) {
 	if personas <= 0 || gpp <= 0 {
 		return nil, fmt.Errorf("personas y gramos por persona deben ser > 0")
 	}
-	totalGrams := float64(personas * gpp)
-
-	// Cebolla por su propio ratio (no afecta proteínas)
-	onionGrams := totalGrams * onionRatio
-
-	// Orden: proteínas + cebolla + bebidas
-	names := []string{
-		"Pulpa de res picada",
-		"Tocino picado",
-		"Jamon en cuadros",
-		"Salchicha p/Asar",
-		"Chorizo",
-		"Cebolla blanca",
-		"Cerveza",
-		"Jugo de verduras V8",
+	if recipeName == "" {
+		recipeName = defaultRecipeName
+	}
+	r, ok := recipe.Get(recipeName)
+	if !ok {
+		return nil, fmt.Errorf("receta desconocida: %s", recipeName)
 	}
 
-	items := make([]IngredientCalc, 0, len(names))
-
-	for _, nm := range names {
-		url := ingredientURLs[nm]
-
-		ctx, cancel := context.WithTimeout(context.Background(), perReqTimeout)
-		pr, err := fetchWithRetry(ctx, url, 3, 800*time.Millisecond)
-		cancel()
-		if err != nil {
-			log.Printf("calc error para %s: %v", nm, err)
-			return nil, fmt.Errorf("%s: %w", nm, err)
-		}
-
-		// Precios crudos
-		unitPrice := 0.0
-		if pr.UnitPrice != nil {
-			unitPrice = *pr.UnitPrice
-		}
-		pricePerKg := 0.0
-		if pr.PricePerKg != nil {
-			pricePerKg = *pr.PricePerKg
-		}
-
-		it := IngredientCalc{
-			Name:       nm,
-			URL:        url,
-			Currency:   pr.Currency,
-			UnitPrice:  unitPrice,
-			PricePerKg: pricePerKg,
-		}
+	totalGrams := float64(personas * gpp)
 
-		switch nm {
-		// ---------- Proteínas por KG ----------
-		case "Pulpa de res picada", "Tocino picado", "Jamon en cuadros":
-			r := proteinRatios[nm]
-			gramsNeeded := r * totalGrams
-			it.GramsNeeded = gramsNeeded
-			kilos := gramsNeeded / 1000.0
+	items := make([]IngredientCalc, 0)
 
-			// Si llegó unit_price pero no price_per_kg, úsalo como $/kg
-			if it.PricePerKg <= 0 && it.UnitPrice > 0 {
-				it.PricePerKg = it.UnitPrice
-			}
-			it.UnitPrice = 0 // UI: solo mostramos $/kg
-			it.Cost = round2(kilos * it.PricePerKg)
-
-		// ---------- Paquetes: Salchicha y Chorizo ----------
-		case "Salchicha p/Asar":
-			// 800 g por paquete — Costo = Precio Unitario × paquetes
-			r := proteinRatios[nm]
-			gramsNeeded := r * totalGrams
-			it.GramsNeeded = gramsNeeded
-			packs := ceilDiv(int(math.Round(gramsNeeded)), 800)
-			it.PurchasedUnits = packs
-
-			if it.UnitPrice <= 0 && it.PricePerKg > 0 {
-				it.UnitPrice = it.PricePerKg
-			}
-			it.PricePerKg = 0
-			it.Cost = round2(float64(packs) * it.UnitPrice)
-
-		case "Chorizo":
-			// 100 g por paquete — Costo = Precio Unitario × paquetes
-			r := proteinRatios[nm]
-			gramsNeeded := r * totalGrams
-			it.GramsNeeded = gramsNeeded
-			packs := ceilDiv(int(math.Round(gramsNeeded)), 100)
-			it.PurchasedUnits = packs
-
-			if it.UnitPrice <= 0 && it.PricePerKg > 0 {
-				it.UnitPrice = it.PricePerKg
-			}
-			it.PricePerKg = 0
-			it.Cost = round2(float64(packs) * it.UnitPrice)
-
-		// ---------- Cebolla ----------
-		case "Cebolla blanca":
-			// Por KG, mostrar $/kg, piezas 150g
-			it.GramsNeeded = onionGrams
-			const onionWeight = 150
-			onions := ceilDiv(int(math.Round(onionGrams)), onionWeight)
-			it.UnitsNeeded = onions
-			if it.PricePerKg <= 0 && it.UnitPrice > 0 {
-				it.PricePerKg = it.UnitPrice
+	for _, group := range r.Groups {
+		groupGrams := totalGrams * group.Ratio
+		for _, it := range group.Items {
+			gramsNeeded := groupGrams * it.Ratio
+			ic, err := calcItem(it, gramsNeeded)
+			if err != nil {
+				log.Printf("calc error para %s: %v", it.Name, err)
+				return nil, fmt.Errorf("%s: %w", it.Name, err)
 			}
-			it.UnitPrice = 0
-			it.Cost = round2(float64(onions*onionWeight) / 1000.0 * it.PricePerKg)
-
-		// ---------- Bebidas ----------
-		case "Cerveza":
-			scale := totalGrams / totalBaseGrams
-			baseLatas := baseUnits[nm] // 3.125
-			latasNecesarias := int(math.Ceil(scale * baseLatas))
-			sixPacks := 0
-			if latasNecesarias > 0 {
-				sixPacks = int(math.Ceil(float64(latasNecesarias) / 6.0))
-				if sixPacks < 1 {
-					sixPacks = 1
-				}
-			}
-			it.UnitsNeeded = latasNecesarias
-			it.PurchasedUnits = sixPacks
-			it.PricePerKg = 0
-			it.Cost = round2(float64(sixPacks) * it.UnitPrice)
-
-		case "Jugo de verduras V8":
-			scale := totalGrams / totalBaseGrams
-			baseLatas := baseUnits[nm]
-			latas := int(math.Ceil(scale * baseLatas))
-			if latas == 0 && scale > 0 {
-				latas = 1
-			}
-			it.UnitsNeeded = latas
-			it.PurchasedUnits = latas
-			it.PricePerKg = 0
-			it.Cost = round2(float64(latas) * it.UnitPrice)
+			nutrition := nutritionFor(ic.Name, ic.GramsNeeded)
+			ic.Nutrition = &nutrition
+			applyIVA(&ic)
+			attachSparkline(&ic)
+			items = append(items, ic)
 		}
+	}
 
-		items = append(items, it)
+	scale := totalGrams / r.BaseTotalGrams
+	for _, bv := range r.Beverages {
+		ic, err := calcBeverage(bv, scale)
+		if err != nil {
+			log.Printf("calc error para %s: %v", bv.Name, err)
+			return nil, fmt.Errorf("%s: %w", bv.Name, err)
+		}
+		nutrition := nutritionFor(ic.Name, ic.GramsNeeded)
+		ic.Nutrition = &nutrition
+		applyIVA(&ic)
+		attachSparkline(&ic)
+		items = append(items, ic)
 	}
 
-	var totalCost float64
+	var subtotal, iva float64
 	currency := "MXN"
 	for _, it := range items {
-		totalCost += it.Cost
+		subtotal += it.Cost
+		iva += it.IVA
 		if it.Currency != "" {
 			currency = it.Currency
 		}
 	}
+	subtotal = round2(subtotal)
+	iva = round2(iva)
 
 	out := &CalcResponse{
 		Personas:         personas,
 		GramosPorPersona: gpp,
 		TotalGramos:      round2(totalGrams),
+		Recipe:           r.Name,
 		Items:            items,
-		TotalCosto:       round2(totalCost),
+		TotalCosto:       subtotal,
+		Subtotal:         subtotal,
+		IVA:              iva,
+		Total:            round2(subtotal + iva),
 		Currency:         currency,
+		Nutrition:        summarizeNutrition(items, personas),
 	}
+	recordCalcHistory(out)
 	return out, nil
 }
 
+// applyIVA calcula la tasa, el monto de IVA y el total con impuesto de un ingrediente.
+func applyIVA(ic *IngredientCalc) {
+	ic.IVARate = ivaRateFor(ic.Name)
+	ic.IVA = round2(ic.Cost * ic.IVARate)
+	ic.Total = round2(ic.Cost + ic.IVA)
+}
+
+// sparklineDays es la ventana de historial que se grafica en cada renglón.
+const sparklineDays = 30
+
+// attachSparkline llena ic.Sparkline con un SVG de los últimos sparklineDays
+// de historial de precio de ic.URL, en verde si bajó o rojo si subió en la
+// última semana. No hace nada si no hay pricestore configurado o no hay URL.
+func attachSparkline(ic *IngredientCalc) {
+	if priceStore == nil || ic.URL == "" {
+		return
+	}
+	until := time.Now()
+	points, err := priceStore.History(ic.URL, until.AddDate(0, 0, -sparklineDays), until)
+	if err != nil || len(points) < 2 {
+		return
+	}
+	changePct, err := priceStore.PercentChange(ic.URL, 7*24*time.Hour)
+	if err != nil {
+		return
+	}
+	ic.Sparkline = renderSparklineSVG(points, changePct)
+}
+
+// renderSparklineSVG dibuja una polilínea de 80x20 a partir de los puntos de
+// precio, coloreada según el cambio porcentual (rojo si subió, verde si bajó).
+func renderSparklineSVG(points []pricestore.Point, changePct float64) template.HTML {
+	const w, h = 80.0, 20.0
+	min, max := points[0].PricePerKg, points[0].PricePerKg
+	for _, p := range points {
+		a := p.PricePerKg
+		if p.PricePerKg == 0 {
+			a = p.UnitPrice
+		}
+		if a < min {
+			min = a
+		}
+		if a > max {
+			max = a
+		}
+	}
+	span := max - min
+	if span == 0 {
+		span = 1
+	}
+
+	var coords strings.Builder
+	for i, p := range points {
+		a := p.PricePerKg
+		if p.PricePerKg == 0 {
+			a = p.UnitPrice
+		}
+		x := float64(i) / float64(len(points)-1) * w
+		y := h - ((a-min)/span)*h
+		if i > 0 {
+			coords.WriteByte(' ')
+		}
+		fmt.Fprintf(&coords, "%.1f,%.1f", x, y)
+	}
+
+	color := "#2ecc71"
+	if changePct > 0 {
+		color = "#e74c3c"
+	}
+	return template.HTML(fmt.Sprintf(
+		`<svg class="sparkline" width="%d" height="%d" viewBox="0 0 %d %d"><polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/></svg>`,
+		int(w), int(h), int(w), int(h), coords.String(), color,
+	))
+}
+
+// buildCart convierte un CalcResponse en un carrito de compras agrupado por retailer.
+func buildCart(res *CalcResponse) *cart.Cart {
+	items := make([]cart.Item, 0, len(res.Items))
+	for _, it := range res.Items {
+		units := it.PurchasedUnits
+		if units == 0 {
+			units = 1
+		}
+		items = append(items, cart.Item{
+			Ingredient: it.Name,
+			Retailer:   it.Retailer,
+			URL:        it.URL,
+			Units:      units,
+			Subtotal:   it.Cost,
+			IVA:        it.IVA,
+			Total:      it.Total,
+		})
+	}
+	return cart.Build(res.Currency, items)
+}
+
 // -------------------- Plantillas HTMX --------------------
 
 // Página principal con HTMX (tema MS-DOS negro/naranja) + acordeones
@@ -511,6 +1360,16 @@ const indexPageHTML = `<!doctype html>
     input[type=number]:focus{
       box-shadow:0 0 0 1px var(--accent-soft);
     }
+    select{
+      padding:8px 10px;
+      font-size:14px;
+      border:1px solid var(--border);
+      border-radius:8px;
+      min-width:140px;
+      background:#000;
+      color:var(--fg);
+      outline:none;
+    }
     button{
       padding:10px 16px;
       border:0;
@@ -575,6 +1434,21 @@ const indexPageHTML = `<!doctype html>
     #resultado[aria-busy="true"]{
       opacity:0.6;
     }
+    .error{
+      display:block;
+      font-size:12px;
+      color:var(--error-fg);
+      min-height:14px;
+    }
+    .export-links{
+      margin-top:8px;
+      font-size:13px;
+      color:var(--fg-soft);
+    }
+    .export-links a{
+      margin-left:6px;
+      color:var(--fg);
+    }
 
     /* Sección de receta */
     .recipe{
@@ -642,6 +1516,35 @@ const indexPageHTML = `<!doctype html>
       opacity:0.85;
     }
 
+    .foodgroup-bar{
+      display:flex;
+      width:100%;
+      height:14px;
+      border-radius:7px;
+      overflow:hidden;
+      margin:10px 0;
+      background:#111111;
+    }
+    .foodgroup-seg{ height:100%; }
+    .foodgroup-carnes{ background:#ff9800; }
+    .foodgroup-lacteos{ background:#ffd54f; }
+    .foodgroup-verduras{ background:#8bc34a; }
+    .foodgroup-bebidas{ background:#4fc3f7; }
+
+    .sparkline{ vertical-align:middle; }
+
+    .compare-row td{ text-align:left; border-bottom:1px solid var(--table-border); }
+    .compare-btn{
+      padding:4px 10px;
+      font-size:12px;
+      background:transparent;
+      border:1px solid var(--border);
+      color:var(--fg-soft);
+      text-transform:none;
+    }
+    .compare-btn:hover{ background:#111111; }
+    .compare-table{ margin-top:8px; }
+
     .farewell{
       margin-top:16px;
     }
@@ -695,21 +1598,59 @@ const indexPageHTML = `<!doctype html>
           hx-target="#resultado"
           hx-swap="innerHTML"
           hx-indicator="#spinner"
-          hx-trigger="submit, keyup changed delay:500ms from:#personas from:#gpp">
+          hx-trigger="submit, keyup changed delay:500ms from:#personas from:#gpp, change from:#recipe">
       <label>Personas
         <input id="personas" name="personas" type="number" value="10" min="1" required>
+        <span class="error" id="err-personas"></span>
       </label>
       <label>Gramos por persona
         <input id="gpp" name="gpp" type="number" value="250" min="50" step="10" required>
+        <span class="error" id="err-gpp"></span>
+      </label>
+      <label>Receta
+        <select id="recipe" name="recipe">
+          <option value="Clásica">Clásica</option>
+          <option value="Mar y Tierra">Mar y Tierra</option>
+          <option value="Sin cerdo">Sin cerdo</option>
+        </select>
       </label>
       <button type="submit" id="btnCalc">Calcular</button>
       <span id="spinner" class="spinner" style="display:none;">Calculando…</span>
     </form>
 
+    <div class="export-links">
+      Descargar:
+      <a href="#" data-ext="csv">CSV</a>
+      <a href="#" data-ext="pdf">PDF</a>
+      <a href="#" data-ext="xml">XML</a>
+      <a href="#" data-ext="yaml">YAML</a>
+      <a href="#" data-ext="json">JSON</a>
+    </div>
+
     <div id="resultado" aria-live="polite" aria-busy="false">
       <!-- Aquí HTMX inyecta la tabla -->
     </div>
 
+    <details class="accordion">
+      <summary>📦 Cálculo en lote (CSV/XLSX)</summary>
+      <div class="accordion-content">
+        <form id="bulkForm"
+              hx-post="/hx/calc/bulk"
+              hx-target="#bulk-results"
+              hx-swap="innerHTML"
+              hx-encoding="multipart/form-data">
+          <label>Archivo (columnas label,personas,gpp)
+            <input type="file" name="file" accept=".csv,.xlsx" required>
+          </label>
+          <button type="submit">Calcular lote</button>
+        </form>
+        <table>
+          <thead><tr><th>Label</th><th>Personas</th><th>GPP</th><th>Receta</th><th>Total</th></tr></thead>
+          <tbody id="bulk-results"></tbody>
+        </table>
+      </div>
+    </details>
+
     <section class="recipe">
       <h2>🍽️ Preparación de la Discada</h2>
 
@@ -816,6 +1757,20 @@ const indexPageHTML = `<!doctype html>
         if (res) res.setAttribute('aria-busy', 'false');
       }
     });
+
+    // Botones de descarga: arman la URL de exportación con los valores actuales del form.
+    document.querySelectorAll('.export-links a').forEach(function (a) {
+      a.addEventListener('click', function (evt) {
+        evt.preventDefault();
+        var personas = document.getElementById('personas').value;
+        var gpp = document.getElementById('gpp').value;
+        var recipe = document.getElementById('recipe').value;
+        var params = 'personas=' + encodeURIComponent(personas) +
+          '&gpp=' + encodeURIComponent(gpp) +
+          '&recipe=' + encodeURIComponent(recipe);
+        window.location.href = '/api/calc.' + a.dataset.ext + '?' + params;
+      });
+    });
   </script>
 </body>
 </html>`
@@ -831,6 +1786,7 @@ var tableTpl = template.Must(template.New("table").Parse(`
       <th>Precio Por Kg</th>
       <th>Precio Unitario</th>
       <th>Costo</th>
+      <th>Tendencia</th>
     </tr>
   </thead>
   <tbody>
@@ -858,31 +1814,307 @@ var tableTpl = template.Must(template.New("table").Parse(`
         {{if gt .UnitPrice 0.0}}${{printf "%.2f" .UnitPrice}}{{else}}-{{end}}
       </td>
       <td style="text-align:right">${{printf "%.2f" .Cost}}</td>
+      <td style="text-align:right">{{if .Sparkline}}{{.Sparkline}}{{else}}-{{end}}</td>
+    </tr>
+    <tr class="compare-row">
+      <td colspan="7">
+        <button type="button" class="compare-btn"
+                hx-get="/hx/compare/{{.Name | urlquery}}"
+                hx-target="next .compare-target"
+                hx-swap="innerHTML">Comparar precios</button>
+        <div class="compare-target"></div>
+      </td>
     </tr>
   {{end}}
   </tbody>
   <tfoot>
     <tr>
-      <td colspan="5" style="text-align:right">Total ({{.Currency}})</td>
-      <td style="text-align:right"><strong>${{printf "%.2f" .TotalCosto}}</strong></td>
+      <td colspan="6" style="text-align:right">Subtotal ({{.Currency}})</td>
+      <td style="text-align:right">${{printf "%.2f" .Subtotal}}</td>
+    </tr>
+    <tr>
+      <td colspan="6" style="text-align:right">IVA</td>
+      <td style="text-align:right">${{printf "%.2f" .IVA}}</td>
+    </tr>
+    <tr>
+      <td colspan="6" style="text-align:right">Total</td>
+      <td style="text-align:right"><strong>${{printf "%.2f" .Total}}</strong></td>
     </tr>
   </tfoot>
 </table>
+
+<details class="accordion">
+  <summary>🥗 Análisis nutricional</summary>
+  <div class="accordion-content">
+    <p>VCT: <strong>{{printf "%.0f" .Nutrition.VCT}} kcal</strong> · {{printf "%.0f" .Nutrition.KcalPerPerson}} kcal/persona</p>
+    <table>
+      <thead>
+        <tr>
+          <th>Macro</th>
+          <th>Total</th>
+        </tr>
+      </thead>
+      <tbody>
+        <tr><td>Proteína</td><td style="text-align:right">{{printf "%.1f" .Nutrition.Totals.ProteinG}} g</td></tr>
+        <tr><td>Carbohidratos</td><td style="text-align:right">{{printf "%.1f" .Nutrition.Totals.CarbsG}} g</td></tr>
+        <tr><td>Grasa</td><td style="text-align:right">{{printf "%.1f" .Nutrition.Totals.FatG}} g</td></tr>
+        <tr><td>Grasa saturada</td><td style="text-align:right">{{printf "%.1f" .Nutrition.Totals.SatFatG}} g</td></tr>
+      </tbody>
+    </table>
+    <div class="foodgroup-bar">
+      {{range .Nutrition.FoodGroups}}
+      <div class="foodgroup-seg foodgroup-{{.Group}}" style="width:{{printf "%.1f" .PercentVCT}}%" title="{{.Group}}: {{printf "%.1f" .PercentVCT}}%"></div>
+      {{end}}
+    </div>
+    <ul>
+      {{range .Nutrition.FoodGroups}}
+      <li>{{.Group}}: {{printf "%.1f" .PercentVCT}}% ({{printf "%.0f" .Kcal}} kcal)</li>
+      {{end}}
+    </ul>
+  </div>
+</details>
+`))
+
+// Partial de comparación de precios entre retailers para un solo ingrediente
+var compareTpl = template.Must(template.New("compare").Funcs(template.FuncMap{
+	"deref": func(f *float64) float64 {
+		if f == nil {
+			return 0
+		}
+		return *f
+	},
+}).Parse(`
+<table class="compare-table">
+  <thead>
+    <tr>
+      <th>Retailer</th>
+      <th>Precio</th>
+    </tr>
+  </thead>
+  <tbody>
+  {{range .}}
+    <tr>
+      <td><a href="{{.URL}}" target="_blank" rel="noopener noreferrer">{{.Scraper}}</a></td>
+      <td style="text-align:right">
+        {{if .PricePerKg}}${{printf "%.2f" (deref .PricePerKg)}}/kg{{else if .UnitPrice}}${{printf "%.2f" (deref .UnitPrice)}}{{else}}-{{end}}
+      </td>
+    </tr>
+  {{end}}
+  </tbody>
+</table>
+`))
+
+// Página de administración: lista recetas registradas y permite subir una nueva.
+var recipesPageTpl = template.Must(template.New("recipes").Parse(`<!doctype html>
+<html lang="es">
+<head>
+  <meta charset="utf-8">
+  <title>Recetas - ccdn.1</title>
+  <style>
+    body{ background:#000; color:#ff9800; font-family:system-ui,sans-serif; }
+    .page{ max-width:700px; margin:24px auto; padding:0 12px; }
+    table{ width:100%; border-collapse:collapse; }
+    th,td{ padding:6px 8px; border-bottom:1px solid #ff980033; text-align:left; }
+    a{ color:#ffb74d; }
+  </style>
+</head>
+<body>
+  <div class="page">
+    <h1>Recetas registradas</h1>
+    <table>
+      <thead><tr><th>Nombre</th><th>Base (g)</th><th>Grupos</th><th>Bebidas</th></tr></thead>
+      <tbody>
+      {{range .}}
+        <tr>
+          <td>{{.Name}}</td>
+          <td>{{printf "%.1f" .BaseTotalGrams}}</td>
+          <td>{{len .Groups}}</td>
+          <td>{{len .Beverages}}</td>
+        </tr>
+      {{end}}
+      </tbody>
+    </table>
+
+    <h2>Subir receta (YAML o JSON)</h2>
+    <form action="/recipes" method="post" enctype="multipart/form-data">
+      <input type="file" name="file" accept=".yaml,.yml,.json" required>
+      <button type="submit">Subir</button>
+    </form>
+  </div>
+</body>
+</html>
+`))
+
+var adminPageTpl = template.Must(template.New("admin").Parse(`<!doctype html>
+<html lang="es">
+<head>
+  <meta charset="utf-8">
+  <title>Admin - ccdn.1</title>
+  <style>
+    body{ background:#000; color:#ff9800; font-family:system-ui,sans-serif; }
+    .page{ max-width:700px; margin:24px auto; padding:0 12px; }
+    table{ width:100%; border-collapse:collapse; }
+    th,td{ padding:6px 8px; border-bottom:1px solid #ff980033; text-align:left; }
+    input{ background:#111; color:#ff9800; border:1px solid #ff980055; padding:4px 6px; }
+  </style>
+</head>
+<body>
+  <div class="page">
+    <h1>Administración</h1>
+
+    <h2>Configuración por defecto</h2>
+    <form action="/admin/config" method="post">
+      <label>GPP por defecto <input type="number" name="default_gpp" value="{{.DefaultGPP}}" min="1"></label>
+      <label>Moneda por defecto <input type="text" name="default_currency" value="{{.DefaultCurrency}}" maxlength="3"></label>
+      <button type="submit">Guardar</button>
+    </form>
+
+    <h2>Cálculos recientes</h2>
+    <table>
+      <thead><tr><th>Fecha</th><th>Personas</th><th>GPP</th><th>Receta</th><th>Total</th></tr></thead>
+      <tbody>
+      {{range .History}}
+        <tr>
+          <td>{{.At.Format "2006-01-02 15:04:05"}}</td>
+          <td>{{.Personas}}</td>
+          <td>{{.GPP}}</td>
+          <td>{{.Recipe}}</td>
+          <td>${{printf "%.2f" .Total}} {{.Currency}}</td>
+        </tr>
+      {{end}}
+      </tbody>
+    </table>
+  </div>
+</body>
+</html>
 `))
 
 // -------------------- HTTP --------------------
 
+// recipeUploadDir es donde se guardan los archivos de receta subidos vía /recipes.
+const recipeUploadDir = "./recipes.d"
+
 func main() {
 	ginMode := mustEnv("GIN_MODE", "debug")
 	if ginMode == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
-	r := gin.Default()
+
+	if path := os.Getenv("RECIPE_FILE"); path != "" {
+		if _, err := recipe.LoadRecipe(path); err != nil {
+			log.Fatalf("cargando RECIPE_FILE=%s: %v", path, err)
+		}
+	}
+	if err := os.MkdirAll(recipeUploadDir, 0o755); err != nil {
+		log.Fatalf("creando %s: %v", recipeUploadDir, err)
+	}
+
+	dbPath := mustEnv("PRICESTORE_PATH", "./pricehistory.db")
+	store, err := pricestore.Open(dbPath)
+	if err != nil {
+		log.Printf("pricestore: no se pudo abrir %s, se sigue sin historial: %v", dbPath, err)
+	} else {
+		priceStore = store
+		defer store.Close()
+	}
+
+	go runAlertChecker(context.Background())
+
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(middleware.RequestID())
+	if mustEnv("ACCESS_LOG", "on") != "off" {
+		r.Use(middleware.AccessLog())
+	}
 	r.SetTrustedProxies(nil)
 
+	// calcRateLimit limita /hx/calc y /api/calc; RATE_LIMIT_PER_MIN=0 lo desactiva.
+	var calcRateLimit gin.HandlerFunc
+	if perMin := atoiQ(mustEnv("RATE_LIMIT_PER_MIN", "30")); perMin > 0 {
+		calcRateLimit = middleware.RateLimiter(perMin)
+	} else {
+		calcRateLimit = func(c *gin.Context) { c.Next() }
+	}
+
 	// Servir estáticos (imagen de despedida, etc.)
 	r.Static("/static", "./static")
 
+	// /admin y /recipes solo se sirven si el operador configuró credenciales
+	// explícitas: fallar cerrado en vez de exponerlos con el admin/discada
+	// por defecto, que es adivinable y da acceso al historial de cálculos y
+	// a reemplazar recetas globales.
+	adminUser, hasAdminUser := os.LookupEnv("ADMIN_USER")
+	adminPass, hasAdminPass := os.LookupEnv("ADMIN_PASS")
+	adminEnabled := hasAdminUser && hasAdminPass
+	if !adminEnabled {
+		log.Println("ADMIN_USER/ADMIN_PASS no están configurados: /admin y /recipes quedan deshabilitados")
+	} else {
+		adminAuth := gin.BasicAuth(gin.Accounts{adminUser: adminPass})
+
+		// /admin: historial de cálculos recientes y ajuste en caliente de los
+		// valores por defecto, protegido con BasicAuth (ADMIN_USER/ADMIN_PASS).
+		admin := r.Group("/admin", adminAuth)
+		admin.GET("", func(c *gin.Context) {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			if err := adminPageTpl.Execute(c.Writer, gin.H{
+				"History":         recentCalcHistory(),
+				"DefaultGPP":      getDefaultGPP(),
+				"DefaultCurrency": getDefaultCurrency(),
+			}); err != nil {
+				log.Println("error ejecutando plantilla de admin:", err)
+			}
+		})
+		admin.POST("/config", func(c *gin.Context) {
+			if v := atoiQ(c.PostForm("default_gpp")); v > 0 {
+				setDefaultGPP(v)
+			}
+			if v := strings.TrimSpace(c.PostForm("default_currency")); v != "" {
+				setDefaultCurrency(v)
+			}
+			c.Redirect(http.StatusSeeOther, "/admin")
+		})
+
+		// Página de administración de recetas: lista las registradas y
+		// permite subir un nuevo archivo YAML/JSON en tiempo de ejecución.
+		// Protegida con la misma BasicAuth que /admin: registrar una receta
+		// reemplaza una global (p.ej. "Clásica") para todos los visitantes.
+		recipes := r.Group("/recipes", adminAuth)
+
+		recipes.GET("", func(c *gin.Context) {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			if err := recipesPageTpl.Execute(c.Writer, recipe.List()); err != nil {
+				log.Println("error ejecutando plantilla de recetas:", err)
+			}
+		})
+
+		recipes.POST("", func(c *gin.Context) {
+			file, err := c.FormFile("file")
+			if err != nil {
+				c.String(http.StatusBadRequest, "archivo requerido: %s", err.Error())
+				return
+			}
+			// filepath.Base descarta cualquier separador de directorio en el
+			// nombre que mandó el cliente, pero un nombre de archivo igual a
+			// ".." sobrevive a Base intacto, así que se rechaza aparte para
+			// que no se pueda escribir fuera de recipeUploadDir.
+			filename := filepath.Base(file.Filename)
+			if filename == "." || filename == ".." || filename == string(filepath.Separator) {
+				c.String(http.StatusBadRequest, "nombre de archivo inválido")
+				return
+			}
+			dst := filepath.Join(recipeUploadDir, filename)
+			if err := c.SaveUploadedFile(file, dst); err != nil {
+				c.String(http.StatusInternalServerError, "guardando archivo: %s", err.Error())
+				return
+			}
+			if _, err := recipe.LoadRecipe(dst); err != nil {
+				c.String(http.StatusUnprocessableEntity, "receta inválida: %s", err.Error())
+				return
+			}
+			c.Redirect(http.StatusSeeOther, "/recipes")
+		})
+	}
+
 	// Página principal HTMX
 	r.GET("/", func(c *gin.Context) {
 		c.Header("Content-Type", "text/html; charset=utf-8")
@@ -890,10 +2122,19 @@ func main() {
 	})
 
 	// Endpoint HTMX: devuelve SOLO la tabla HTML
-	r.POST("/hx/calc", func(c *gin.Context) {
-		personas := atoiQ(c.PostForm("personas"))
-		gpp := atoiQ(c.PostForm("gpp"))
-		res, err := calcFor(personas, gpp)
+	r.POST("/hx/calc", calcRateLimit, func(c *gin.Context) {
+		var req CalcRequest
+		if err := c.ShouldBind(&req); err != nil {
+			errs := translateValidationErrors(err, "es")
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.Status(http.StatusUnprocessableEntity)
+			for _, field := range []string{"personas", "gpp"} {
+				fmt.Fprintf(c.Writer, `<span class="error" hx-swap-oob="true" id="err-%s">%s</span>`,
+					field, template.HTMLEscapeString(errs[field]))
+			}
+			return
+		}
+		res, err := calcFor(req.Personas, req.GPP, req.Recipe)
 		if err != nil {
 			c.Header("Content-Type", "text/html; charset=utf-8")
 			c.String(http.StatusBadRequest, `<div class="toast">Error: %s</div>`, template.HTMLEscapeString(err.Error()))
@@ -905,16 +2146,240 @@ func main() {
 		}
 	})
 
-	// Endpoint JSON original (por si lo sigues usando)
-	r.GET("/api/calc", func(c *gin.Context) {
+	// Partial HTMX: tabla de comparación de precios de un ingrediente entre retailers
+	r.GET("/hx/compare/:ingredient", func(c *gin.Context) {
+		name := c.Param("ingredient")
+		urls, ok := recipeItemURLs(name)
+		if !ok {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.String(http.StatusNotFound, `<div class="toast">Ingrediente desconocido: %s</div>`, template.HTMLEscapeString(name))
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), perReqTimeout)
+		defer cancel()
+		_, alternates, err := fetchCheapest(ctx, name, urls)
+		if err != nil {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.String(http.StatusBadGateway, `<div class="toast">Error: %s</div>`, template.HTMLEscapeString(err.Error()))
+			return
+		}
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		if err := compareTpl.Execute(c.Writer, alternates); err != nil {
+			log.Println("error ejecutando template de comparación:", err)
+		}
+	})
+
+	// Endpoint de exportación: el mismo cálculo en JSON, XML, YAML, CSV o PDF,
+	// negociado por Accept, ?format= o la extensión de /api/calc.:ext.
+	r.GET("/api/calc", calcRateLimit, calcExportHandler)
+	r.GET("/api/calc.:ext", calcRateLimit, calcExportHandler)
+
+	// Endpoint JSON: solo el desglose nutricional (sin la tabla de costos)
+	r.GET("/api/nutrition", func(c *gin.Context) {
+		personas := atoiQ(c.Query("personas"))
+		gpp := atoiQ(c.Query("gpp"))
+		res, err := calcFor(personas, gpp, c.Query("recipe"))
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, res.Nutrition)
+	})
+
+	// Endpoint HTMX: sube un CSV/XLSX con columnas label,personas,gpp y
+	// transmite un <tr> por fila procesada a medida que se calcula.
+	r.POST("/hx/calc/bulk", func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bulkMaxUploadBytes)
+
+		var req bulkUploadRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.String(http.StatusBadRequest, `<tr><td colspan="5" class="toast">Error: %s</td></tr>`,
+				template.HTMLEscapeString(err.Error()))
+			return
+		}
+		rows, rowErrs, err := parseBulkUpload(req.File)
+		if err != nil {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.String(http.StatusBadRequest, `<tr><td colspan="5" class="toast">Error: %s</td></tr>`,
+				template.HTMLEscapeString(err.Error()))
+			return
+		}
+		if len(rowErrs) > 0 {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.Status(http.StatusUnprocessableEntity)
+			for _, re := range rowErrs {
+				fmt.Fprintf(c.Writer, `<tr><td colspan="5" class="toast">%s</td></tr>`, template.HTMLEscapeString(re.Error()))
+			}
+			return
+		}
+		if len(rows) == 0 {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.String(http.StatusBadRequest, `<tr><td colspan="5" class="toast">El archivo no tiene filas para calcular</td></tr>`)
+			return
+		}
+
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		i := 0
+		c.Stream(func(w io.Writer) bool {
+			row := rows[i]
+			i++
+			res, err := calcFor(row.Personas, row.GPP, req.Recipe)
+			if err != nil {
+				fmt.Fprintf(w, `<tr><td>%s</td><td colspan="4" class="toast">Error: %s</td></tr>`,
+					template.HTMLEscapeString(row.Label), template.HTMLEscapeString(err.Error()))
+			} else {
+				fmt.Fprintf(w, `<tr><td>%s</td><td>%d</td><td>%d</td><td>%s</td><td>$%.2f %s</td></tr>`,
+					template.HTMLEscapeString(row.Label), res.Personas, res.GramosPorPersona, res.Recipe, res.Total, res.Currency)
+			}
+			return i < len(rows)
+		})
+	})
+
+	// Endpoint JSON: igual que /hx/calc/bulk pero emite NDJSON, un objeto por fila.
+	r.POST("/api/calc/bulk", func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, bulkMaxUploadBytes)
+
+		var req bulkUploadRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rows, rowErrs, err := parseBulkUpload(req.File)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(rowErrs) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"errors": rowErrs})
+			return
+		}
+
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		c.Status(http.StatusOK)
+		enc := json.NewEncoder(c.Writer)
+		flusher, _ := c.Writer.(http.Flusher)
+		for _, row := range rows {
+			res, err := calcFor(row.Personas, row.GPP, req.Recipe)
+			var line gin.H
+			if err != nil {
+				line = gin.H{"label": row.Label, "error": err.Error()}
+			} else {
+				line = gin.H{"label": row.Label, "result": res}
+			}
+			if err := enc.Encode(line); err != nil {
+				log.Printf("bulk: error escribiendo NDJSON: %v", err)
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	})
+
+	// /cart: lista de compras agrupada por retailer, exportable en texto, Markdown o CSV.
+	r.GET("/cart", func(c *gin.Context) {
+		personas := atoiQ(c.Query("personas"))
+		gpp := atoiQ(c.Query("gpp"))
+		res, err := calcFor(personas, gpp, c.Query("recipe"))
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		ct := buildCart(res)
+
+		switch c.DefaultQuery("format", "text") {
+		case "markdown", "md":
+			c.Header("Content-Type", "text/markdown; charset=utf-8")
+			c.String(200, ct.Markdown())
+		case "csv":
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Header("Content-Disposition", `attachment; filename="carrito.csv"`)
+			c.String(200, ct.CSV())
+		default:
+			c.Header("Content-Type", "text/plain; charset=utf-8")
+			c.String(200, ct.Text())
+		}
+	})
+
+	// /api/fx: convierte los totales de la receta a otra divisa (USD, EUR, ...).
+	r.GET("/api/fx", func(c *gin.Context) {
 		personas := atoiQ(c.Query("personas"))
 		gpp := atoiQ(c.Query("gpp"))
-		res, err := calcFor(personas, gpp)
+		res, err := calcFor(personas, gpp, c.Query("recipe"))
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		target := c.DefaultQuery("currency", mustEnv("CURRENCY", "USD"))
+		provider := fx.Select()
+		converted, err := fx.Convert(c.Request.Context(), provider, res.Total, res.Currency, target)
 		if err != nil {
 			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(200, res)
+		c.JSON(200, gin.H{
+			"from":      res.Currency,
+			"to":        target,
+			"provider":  provider.Name(),
+			"total":     res.Total,
+			"converted": round2(converted),
+		})
+	})
+
+	// /config.json: lo que el frontend necesita saber sobre monedas/impuestos/exportaciones soportadas.
+	r.GET("/config.json", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"currencies":       []string{"MXN", "USD", "EUR"},
+			"default_currency": getDefaultCurrency(),
+			"default_gpp":      getDefaultGPP(),
+			"tax_mode":         "iva_mx",
+			"export_formats":   []string{"text", "markdown", "csv"},
+		})
+	})
+
+	// /api/history: serie de tiempo de precios de una URL ya scrapeada.
+	r.GET("/api/history", func(c *gin.Context) {
+		if priceStore == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "pricestore no disponible"})
+			return
+		}
+		url := c.Query("url")
+		if url == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "falta ?url="})
+			return
+		}
+		days := atoiQ(c.DefaultQuery("days", "30"))
+		if days <= 0 {
+			days = 30
+		}
+		until := time.Now()
+		since := until.AddDate(0, 0, -days)
+		points, err := priceStore.History(url, since, until)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, points)
+	})
+
+	// /api/alerts: registra un umbral de cambio de precio para una URL, con
+	// webhook (o correo, solo registrado por ahora) de notificación.
+	r.POST("/api/alerts", func(c *gin.Context) {
+		var req alertRequest
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.HasPrefix(req.EmailOrWebhook, "http://") || strings.HasPrefix(req.EmailOrWebhook, "https://") {
+			if !isSafeWebhookURL(req.EmailOrWebhook) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "email_or_webhook debe ser un host público alcanzable, no una IP privada/local"})
+				return
+			}
+		}
+		registerAlert(req)
+		c.JSON(http.StatusCreated, gin.H{"status": "registrada"})
 	})
 
 	port := mustEnv("PORT", "8080")
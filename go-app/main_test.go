@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"net"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSummarizeNutritionOrdenDeFoodGroupsEstable(t *testing.T) {
+	items := []IngredientCalc{
+		{Name: "Cerveza", Nutrition: &NutritionTotals{Kcal: 100, ProteinG: 1, CarbsG: 10, FatG: 0}},
+		{Name: "Pulpa de res picada", Nutrition: &NutritionTotals{Kcal: 500, ProteinG: 50, CarbsG: 0, FatG: 30}},
+		{Name: "Cebolla blanca", Nutrition: &NutritionTotals{Kcal: 40, ProteinG: 1, CarbsG: 9, FatG: 0}},
+	}
+
+	want := []string{"bebidas", "carnes", "verduras"}
+	for run := 0; run < 5; run++ {
+		got := summarizeNutrition(items, 4)
+		if len(got.FoodGroups) != 3 {
+			t.Fatalf("esperaba 3 food groups, obtuve %d", len(got.FoodGroups))
+		}
+		for i, fg := range got.FoodGroups {
+			if fg.Group != want[i] {
+				t.Fatalf("orden de food_groups no determinista: en la corrida %d obtuve %v, quería %v", run, groupNamesOf(got.FoodGroups), want)
+			}
+		}
+	}
+}
+
+func groupNamesOf(groups []FoodGroupShare) []string {
+	out := make([]string, len(groups))
+	for i, g := range groups {
+		out[i] = g.Group
+	}
+	return out
+}
+
+func TestNegotiateAcceptNavegadorCaeAJSON(t *testing.T) {
+	// Accept por defecto de Chrome: trae application/xml pero no debe
+	// negociar XML, porque su calidad (q=0.9) no supera la de text/html
+	// (q=1.0 implícito).
+	accept := "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+	if format, ok := negotiateAccept(accept); ok {
+		t.Fatalf("esperaba que el Accept de navegador no negocie ningún formato soportado, obtuve %q", format)
+	}
+}
+
+func TestNegotiateAcceptRespetaCalidad(t *testing.T) {
+	format, ok := negotiateAccept("application/json;q=0.5, text/csv;q=0.9")
+	if !ok || format != "csv" {
+		t.Fatalf("negotiateAccept = (%q, %v), quería (\"csv\", true)", format, ok)
+	}
+}
+
+func TestCalcResponseXMLYAMLUsanClavesSnakeCase(t *testing.T) {
+	res := CalcResponse{
+		Personas:         4,
+		GramosPorPersona: 250,
+		TotalGramos:      1000,
+		Currency:         "MXN",
+	}
+
+	xmlOut, err := xml.Marshal(res)
+	if err != nil {
+		t.Fatalf("xml.Marshal: %v", err)
+	}
+	if !strings.Contains(string(xmlOut), "<gramos_por_persona>") {
+		t.Fatalf("XML no usa la clave snake_case gramos_por_persona, salió: %s", xmlOut)
+	}
+
+	yamlOut, err := yaml.Marshal(res)
+	if err != nil {
+		t.Fatalf("yaml.Marshal: %v", err)
+	}
+	if !strings.Contains(string(yamlOut), "gramos_por_persona:") {
+		t.Fatalf("YAML no usa la clave snake_case gramos_por_persona, salió: %s", yamlOut)
+	}
+}
+
+func TestIsPrivateOrLocalIP(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", false},
+		{"127.0.0.1", true},
+		{"10.0.0.5", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // endpoint de metadata de nube
+		{"0.0.0.0", true},
+		{"::1", true},
+	}
+	for _, tc := range cases {
+		ip := net.ParseIP(tc.ip)
+		if ip == nil {
+			t.Fatalf("net.ParseIP(%q) falló", tc.ip)
+		}
+		if got := isPrivateOrLocalIP(ip); got != tc.want {
+			t.Errorf("isPrivateOrLocalIP(%s) = %v, quería %v", tc.ip, got, tc.want)
+		}
+	}
+}
+
+func TestDialPublicOnlyRechazaIPPrivada(t *testing.T) {
+	if _, err := dialPublicOnly(context.Background(), "tcp", "127.0.0.1:80"); err == nil {
+		t.Fatal("dialPublicOnly debería rechazar conectar a una IP loopback")
+	}
+}
+
+func TestSummarizeNutritionVCTAtwater(t *testing.T) {
+	items := []IngredientCalc{
+		{Name: "Pulpa de res picada", Nutrition: &NutritionTotals{Kcal: 500, ProteinG: 50, CarbsG: 10, FatG: 30}},
+	}
+	got := summarizeNutrition(items, 2)
+
+	wantVCT := 50*4.0 + 10*4.0 + 30*9.0
+	if got.VCT != round2(wantVCT) {
+		t.Fatalf("VCT = %v, quería %v (Atwater 4/4/9)", got.VCT, round2(wantVCT))
+	}
+	if got.KcalPerPerson != round2(wantVCT/2) {
+		t.Fatalf("KcalPerPerson = %v, quería %v", got.KcalPerPerson, round2(wantVCT/2))
+	}
+}
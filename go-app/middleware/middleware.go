@@ -0,0 +1,162 @@
+// Package middleware trae los handlers de Gin compartidos por main: request
+// ID, log de acceso en JSON y rate limiting por IP.
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader es el header donde se lee/escribe el ID de cada request.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID asegura que cada request tenga un X-Request-ID, generando uno
+// nuevo si el cliente no mandó ninguno.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Writer.Header().Set(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("t%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// accessLogEntry es la línea JSON emitida por request en AccessLog.
+type accessLogEntry struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	ClientIP  string `json:"client_ip"`
+	RequestID string `json:"request_id"`
+}
+
+// AccessLog reemplaza el logger de texto de gin.Default() por una línea JSON
+// por request, pensada para agregarse fácil en un colector de logs.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		// Igual que el logger de Gin: truncamos la latencia a milisegundos.
+		latency := time.Since(start).Truncate(time.Millisecond)
+		entry := accessLogEntry{
+			Method:    c.Request.Method,
+			Path:      path,
+			Status:    c.Writer.Status(),
+			LatencyMs: latency.Milliseconds(),
+			ClientIP:  c.ClientIP(),
+			RequestID: fmt.Sprint(c.GetString("request_id")),
+		}
+		line, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("access-log: error serializando entrada: %v", err)
+			return
+		}
+		log.Println(string(line))
+	}
+}
+
+// bucket es un token bucket simple: se recarga linealmente con el tiempo y
+// se consume 1 token por request.
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// bucketIdleTTL es cuánto puede estar un bucket sin requests antes de que
+// bucketSweeper lo elimine; bucketSweepEvery es cada cuánto se barre el mapa.
+// Sin esto, buckets crece sin límite: una IP distinta por request (spoofing,
+// scanners) nunca libera memoria durante la vida del proceso.
+const (
+	bucketIdleTTL    = 10 * time.Minute
+	bucketSweepEvery = 5 * time.Minute
+)
+
+// bucketSweeper borra periódicamente los buckets que llevan más de
+// bucketIdleTTL sin recibir una request, para que el mapa no crezca sin
+// límite por IP.
+func bucketSweeper(mu *sync.Mutex, buckets map[string]*bucket) {
+	for range time.Tick(bucketSweepEvery) {
+		cutoff := time.Now().Add(-bucketIdleTTL)
+		mu.Lock()
+		for ip, b := range buckets {
+			if b.lastSeen.Before(cutoff) {
+				delete(buckets, ip)
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+// RateLimiter limita a `perMinute` requests por minuto por IP de cliente,
+// respondiendo 429 con Retry-After cuando se agotan los tokens. Si la
+// petición viene de HTMX (header HX-Request), responde un fragmento HTML de
+// toast (un <div class="toast"> sin hx-swap-oob) en vez de JSON.
+func RateLimiter(perMinute int) gin.HandlerFunc {
+	var (
+		mu      sync.Mutex
+		buckets = make(map[string]*bucket)
+	)
+	refillPerSecond := float64(perMinute) / 60.0
+	go bucketSweeper(&mu, buckets)
+
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+
+		mu.Lock()
+		b, ok := buckets[ip]
+		now := time.Now()
+		if !ok {
+			b = &bucket{tokens: float64(perMinute), lastSeen: now}
+			buckets[ip] = b
+		} else {
+			elapsed := now.Sub(b.lastSeen).Seconds()
+			b.tokens += elapsed * refillPerSecond
+			if b.tokens > float64(perMinute) {
+				b.tokens = float64(perMinute)
+			}
+			b.lastSeen = now
+		}
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		mu.Unlock()
+
+		if allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := "60"
+		c.Header("Retry-After", retryAfter)
+		if c.GetHeader("HX-Request") == "true" {
+			c.Header("Content-Type", "text/html; charset=utf-8")
+			c.String(http.StatusTooManyRequests, `<div class="toast">Demasiadas solicitudes, espera un momento.</div>`)
+		} else {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "demasiadas solicitudes, intenta de nuevo más tarde"})
+		}
+		c.Abort()
+	}
+}
@@ -0,0 +1,138 @@
+// Package pricestore persiste el historial de precios scrapeados en SQLite
+// (vía modernc.org/sqlite, puro Go) para poder graficar tendencias y disparar
+// alertas, en vez de tirar cada precio al expirar la cache de 5 minutos.
+package pricestore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Point es un precio observado de una URL en un instante dado.
+type Point struct {
+	URL        string    `json:"url"`
+	Ts         time.Time `json:"ts"`
+	PricePerKg float64   `json:"price_per_kg,omitempty"`
+	UnitPrice  float64   `json:"unit_price,omitempty"`
+	Currency   string    `json:"currency"`
+}
+
+// Store envuelve la base SQLite de historial de precios.
+type Store struct {
+	db *sql.DB
+}
+
+// Open abre (o crea) la base SQLite en `path` y asegura el esquema.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("abriendo %s: %w", path, err)
+	}
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS price_history (
+			url TEXT NOT NULL,
+			ts INTEGER NOT NULL,
+			price_per_kg REAL NOT NULL DEFAULT 0,
+			unit_price REAL NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_price_history_url_ts ON price_history(url, ts);
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creando esquema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error { return s.db.Close() }
+
+// Record agrega una observación de precio para una URL.
+func (s *Store) Record(url string, ts time.Time, pricePerKg, unitPrice float64, currency string) error {
+	_, err := s.db.Exec(
+		`INSERT INTO price_history (url, ts, price_per_kg, unit_price, currency) VALUES (?, ?, ?, ?, ?)`,
+		url, ts.Unix(), pricePerKg, unitPrice, currency,
+	)
+	if err != nil {
+		return fmt.Errorf("insertando historial de %s: %w", url, err)
+	}
+	return nil
+}
+
+// History devuelve los puntos de precio de una URL entre `since` y `until`, ordenados por tiempo.
+func (s *Store) History(url string, since, until time.Time) ([]Point, error) {
+	rows, err := s.db.Query(
+		`SELECT ts, price_per_kg, unit_price, currency FROM price_history
+		 WHERE url = ? AND ts BETWEEN ? AND ? ORDER BY ts ASC`,
+		url, since.Unix(), until.Unix(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("consultando historial de %s: %w", url, err)
+	}
+	defer rows.Close()
+
+	var points []Point
+	for rows.Next() {
+		var ts int64
+		p := Point{URL: url}
+		if err := rows.Scan(&ts, &p.PricePerKg, &p.UnitPrice, &p.Currency); err != nil {
+			return nil, fmt.Errorf("leyendo fila de historial: %w", err)
+		}
+		p.Ts = time.Unix(ts, 0).UTC()
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// amount devuelve el valor comparable de un Point (precio por kg o unitario).
+func (p Point) amount() float64 {
+	if p.PricePerKg > 0 {
+		return p.PricePerKg
+	}
+	return p.UnitPrice
+}
+
+// MinMaxAvg resume el rango de precios de una URL entre `since` y `until`.
+func (s *Store) MinMaxAvg(url string, since, until time.Time) (min, max, avg float64, err error) {
+	points, err := s.History(url, since, until)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if len(points) == 0 {
+		return 0, 0, 0, nil
+	}
+	min, max = points[0].amount(), points[0].amount()
+	var sum float64
+	for _, p := range points {
+		a := p.amount()
+		if a < min {
+			min = a
+		}
+		if a > max {
+			max = a
+		}
+		sum += a
+	}
+	return min, max, sum / float64(len(points)), nil
+}
+
+// PercentChange compara el precio más reciente contra el de hace `window`,
+// devolviendo el cambio porcentual (positivo = subió).
+func (s *Store) PercentChange(url string, window time.Duration) (float64, error) {
+	now := time.Now()
+	points, err := s.History(url, now.Add(-window), now)
+	if err != nil {
+		return 0, err
+	}
+	if len(points) < 2 {
+		return 0, nil
+	}
+	first := points[0].amount()
+	last := points[len(points)-1].amount()
+	if first == 0 {
+		return 0, nil
+	}
+	return (last - first) / first * 100, nil
+}
@@ -0,0 +1,126 @@
+package recipe
+
+// Las recetas por defecto se registran al arrancar para que la app funcione
+// sin necesidad de RECIPE_FILE; un archivo externo puede sobreescribirlas
+// (RegisterRecipe reemplaza por Name).
+func init() {
+	RegisterRecipe(clasica())
+	RegisterRecipe(marYTierra())
+	RegisterRecipe(sinCerdo())
+}
+
+func clasica() *Recipe {
+	return &Recipe{
+		Name:           "Clásica",
+		BaseTotalGrams: 2937.5,
+		Groups: []Group{
+			{
+				Name:  "proteinas",
+				Ratio: 1.0,
+				Items: []Item{
+					{Name: "Pulpa de res picada", Ratio: 0.55, UnitType: "kg",
+						URLs: []string{
+							"https://alsuper.com/producto/pulpa-de-res-picada-357825",
+							"https://www.soriana.com/pulpa-de-res-picada-kg",
+							"https://www.walmart.com.mx/ip/pulpa-de-res-picada-kg",
+						}},
+					{Name: "Tocino picado", Ratio: 0.075, UnitType: "kg",
+						URLs: []string{
+							"https://alsuper.com/producto/tocineta-413218",
+							"https://www.soriana.com/tocino-picado-kg",
+						}},
+					{Name: "Jamon en cuadros", Ratio: 0.175, UnitType: "kg",
+						URLs: []string{
+							"https://alsuper.com/producto/jamon-de-pierna-horneado-428669",
+							"https://www.walmart.com.mx/ip/jamon-de-pierna-en-cuadros-kg",
+						}},
+					{Name: "Salchicha p/Asar", Ratio: 0.125, UnitType: "pack", PackSizeG: 800,
+						URLs: []string{
+							"https://alsuper.com/producto/salchicha-para-asar-238828",
+							"https://www.bodegaaurrera.com.mx/ip/salchicha-para-asar-800g",
+						}},
+					{Name: "Chorizo", Ratio: 0.075, UnitType: "pack", PackSizeG: 100,
+						URLs: []string{
+							"https://alsuper.com/producto/chorizo-319544",
+							"https://www.soriana.com/chorizo-100g",
+						}},
+				},
+			},
+			{
+				Name:  "cebolla",
+				Ratio: 0.175,
+				Items: []Item{
+					{Name: "Cebolla blanca", Ratio: 1.0, UnitType: "kg", PackSizeG: 150,
+						URLs: []string{
+							"https://alsuper.com/producto/cebolla-blanca-924",
+							"https://www.walmart.com.mx/ip/cebolla-blanca-kg",
+							"https://www.bodegaaurrera.com.mx/ip/cebolla-blanca-kg",
+						}},
+				},
+			},
+		},
+		Beverages: []Beverage{
+			{Name: "Cerveza", BaseUnits: 3.125, UnitSizeMl: 355, PackSize: 6,
+				URLs: []string{
+					"https://alsuper.com/producto/cerveza-six-pack-lata-323328",
+					"https://www.soriana.com/cerveza-six-pack-lata",
+				}},
+			{Name: "Jugo de verduras V8", BaseUnits: 1.0, UnitSizeMl: 237, PackSize: 1,
+				URLs: []string{
+					"https://alsuper.com/producto/nectar-mixto-de-450697",
+					"https://www.walmart.com.mx/ip/nectar-mixto-v8",
+				}},
+		},
+	}
+}
+
+// marYTierra agrega camarón a razón de 1 kg por cada 2 kg de pulpa de res,
+// haciéndole espacio dentro del grupo al encoger proporcionalmente las demás
+// proteínas para que el grupo siga sumando 1.0 (mismo criterio que sinCerdo).
+func marYTierra() *Recipe {
+	r := clasica()
+	r.Name = "Mar y Tierra"
+	proteinas := &r.Groups[0]
+	// Pulpa ocupa 0.55 del grupo; el camarón entra a la mitad de esa proporción
+	// (1 kg de camarón por cada 2 kg de pulpa). Como la pulpa también se encoge,
+	// se escala todo por 1/(1+0.275) para que camarón = pulpa/2 y el grupo sume 1.0.
+	camaronShare := 0.55 / 2
+	shrink := 1 / (1 + camaronShare)
+	camaronRatio := camaronShare * shrink
+	for i := range proteinas.Items {
+		proteinas.Items[i].Ratio *= shrink
+	}
+	proteinas.Items = append(proteinas.Items, Item{
+		Name: "Camarón pelado", Ratio: camaronRatio, UnitType: "kg",
+		URLs: []string{
+			"https://alsuper.com/producto/camaron-pelado-crudo-kg",
+			"https://www.walmart.com.mx/ip/camaron-pelado-crudo-kg",
+		},
+	})
+	return r
+}
+
+// sinCerdo quita tocino, jamón y chorizo (de origen porcino) y redistribuye su
+// proporción hacia la pulpa de res.
+func sinCerdo() *Recipe {
+	r := clasica()
+	r.Name = "Sin cerdo"
+	proteinas := &r.Groups[0]
+	kept := make([]Item, 0, len(proteinas.Items))
+	var removedRatio float64
+	for _, it := range proteinas.Items {
+		switch it.Name {
+		case "Tocino picado", "Jamon en cuadros", "Chorizo":
+			removedRatio += it.Ratio
+		default:
+			kept = append(kept, it)
+		}
+	}
+	for i := range kept {
+		if kept[i].Name == "Pulpa de res picada" {
+			kept[i].Ratio += removedRatio
+		}
+	}
+	proteinas.Items = kept
+	return r
+}
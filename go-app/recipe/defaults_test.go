@@ -0,0 +1,39 @@
+package recipe
+
+import "testing"
+
+// groupRatioSum suma los Ratio de los items de un grupo; usado para verificar
+// que las variantes derivadas de clasica() no rompan la invariante de que
+// cada grupo debe sumar 1.0 (de lo contrario TotalGramos reportado ya no
+// corresponde a lo que realmente se compra).
+func groupRatioSum(g Group) float64 {
+	var sum float64
+	for _, it := range g.Items {
+		sum += it.Ratio
+	}
+	return sum
+}
+
+func TestMarYTierraProteinasSumaUno(t *testing.T) {
+	r := marYTierra()
+	got := groupRatioSum(r.Groups[0])
+	if diff := got - 1.0; diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("proteinas de Mar y Tierra suma %.6f, quería 1.0", got)
+	}
+}
+
+func TestSinCerdoProteinasSumaUno(t *testing.T) {
+	r := sinCerdo()
+	got := groupRatioSum(r.Groups[0])
+	if diff := got - 1.0; diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("proteinas de Sin cerdo suma %.6f, quería 1.0", got)
+	}
+}
+
+func TestClasicaProteinasSumaUno(t *testing.T) {
+	r := clasica()
+	got := groupRatioSum(r.Groups[0])
+	if diff := got - 1.0; diff > 1e-9 || diff < -1e-9 {
+		t.Fatalf("proteinas de Clásica suma %.6f, quería 1.0", got)
+	}
+}
@@ -0,0 +1,111 @@
+// Package recipe define el formato externo de receta (YAML o JSON) que
+// reemplaza las proporciones que antes estaban codificadas en main.go, y
+// permite registrar variantes (Clásica, Mar y Tierra, Sin cerdo, etc.).
+package recipe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Item es un ingrediente dentro de un Group: su proporción dentro del grupo,
+// cómo se empaqueta/vende, y las URLs candidatas de retailers para cotizarlo.
+type Item struct {
+	Name      string   `yaml:"name" json:"name"`
+	Ratio     float64  `yaml:"ratio" json:"ratio"`
+	PackSizeG float64  `yaml:"pack_size_g" json:"pack_size_g"`
+	UnitType  string   `yaml:"unit_type" json:"unit_type"` // "kg" o "pack"
+	URLs      []string `yaml:"urls" json:"urls"`
+}
+
+// Group es un bloque de ingredientes que juntos representan `Ratio` del total
+// de gramos de la receta (p.ej. "proteinas" = 1.0, "cebolla" = 0.175 aparte).
+type Group struct {
+	Name  string  `yaml:"name" json:"name"`
+	Ratio float64 `yaml:"ratio" json:"ratio"`
+	Items []Item  `yaml:"items" json:"items"`
+}
+
+// Beverage se escala por separado, en proporción al tamaño total de la receta
+// respecto a BaseTotalGrams, no como fracción directa de los gramos de carne.
+type Beverage struct {
+	Name       string   `yaml:"name" json:"name"`
+	BaseUnits  float64  `yaml:"base_units" json:"base_units"`
+	UnitSizeMl float64  `yaml:"unit_size_ml" json:"unit_size_ml"`
+	PackSize   int      `yaml:"pack_size" json:"pack_size"` // p.ej. 6 para six-pack; 0/1 = sin empaque
+	URLs       []string `yaml:"urls" json:"urls"`
+}
+
+// Recipe es una variante completa de discada (o plato similar) cargable desde archivo.
+type Recipe struct {
+	Name           string     `yaml:"name" json:"name"`
+	BaseTotalGrams float64    `yaml:"base_total_grams" json:"base_total_grams"`
+	Groups         []Group    `yaml:"groups" json:"groups"`
+	Beverages      []Beverage `yaml:"beverages" json:"beverages"`
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]*Recipe)
+)
+
+// RegisterRecipe agrega o reemplaza una receta en el registro en memoria, bajo su Name.
+func RegisterRecipe(r *Recipe) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[r.Name] = r
+}
+
+// Get busca una receta registrada por nombre.
+func Get(name string) (*Recipe, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	r, ok := registry[name]
+	return r, ok
+}
+
+// List devuelve todas las recetas registradas, para la página de administración.
+func List() []*Recipe {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]*Recipe, 0, len(registry))
+	for _, r := range registry {
+		out = append(out, r)
+	}
+	return out
+}
+
+// LoadRecipe lee un archivo YAML o JSON (según su extensión) y lo registra.
+// Las extensiones soportadas son .yaml, .yml y .json.
+func LoadRecipe(path string) (*Recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("leyendo %s: %w", path, err)
+	}
+
+	var r Recipe
+	switch ext := strings.ToLower(path[strings.LastIndex(path, ".")+1:]); ext {
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("parseando YAML de %s: %w", path, err)
+		}
+	case "json":
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("parseando JSON de %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("extensión no soportada para receta: %s", path)
+	}
+
+	if r.Name == "" {
+		return nil, fmt.Errorf("la receta en %s no tiene \"name\"", path)
+	}
+
+	RegisterRecipe(&r)
+	return &r, nil
+}
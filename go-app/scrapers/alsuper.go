@@ -0,0 +1,29 @@
+package scrapers
+
+import (
+	"context"
+	"strings"
+)
+
+// alsuper extrae precios directamente del HTML público de alsuper.com.
+type alsuper struct{}
+
+func newAlsuper() *alsuper { return &alsuper{} }
+
+func (a *alsuper) Name() string { return "alsuper" }
+
+func (a *alsuper) Matches(url string) bool {
+	return strings.Contains(url, "alsuper.com")
+}
+
+func (a *alsuper) Fetch(ctx context.Context, url string) (*Price, error) {
+	html, err := fetchHTML(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	price, err := priceNear(html, "as-discount-price", "as-product-price")
+	if err != nil {
+		return nil, err
+	}
+	return &Price{Scraper: a.Name(), URL: url, Currency: "MXN", UnitPrice: &price}, nil
+}
@@ -0,0 +1,29 @@
+package scrapers
+
+import (
+	"context"
+	"strings"
+)
+
+// bodegaAurrera extrae precios del HTML público de bodegaaurrera.com.mx.
+type bodegaAurrera struct{}
+
+func newBodegaAurrera() *bodegaAurrera { return &bodegaAurrera{} }
+
+func (b *bodegaAurrera) Name() string { return "bodega_aurrera" }
+
+func (b *bodegaAurrera) Matches(url string) bool {
+	return strings.Contains(url, "bodegaaurrera.com.mx")
+}
+
+func (b *bodegaAurrera) Fetch(ctx context.Context, url string) (*Price, error) {
+	html, err := fetchHTML(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	price, err := priceNear(html, "price-main", "current-price")
+	if err != nil {
+		return nil, err
+	}
+	return &Price{Scraper: b.Name(), URL: url, Currency: "MXN", UnitPrice: &price}, nil
+}
@@ -0,0 +1,130 @@
+// Package scrapers implementa la obtención de precios de distintos
+// supermercados mexicanos a partir de la URL de un producto.
+package scrapers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Price es el resultado normalizado de extraer el precio de una página de producto.
+type Price struct {
+	Scraper    string   `json:"scraper" xml:"scraper" yaml:"scraper"`
+	URL        string   `json:"url" xml:"url" yaml:"url"`
+	Product    *string  `json:"product_name,omitempty" xml:"product_name,omitempty" yaml:"product_name,omitempty"`
+	PricePerKg *float64 `json:"price_per_kg,omitempty" xml:"price_per_kg,omitempty" yaml:"price_per_kg,omitempty"` // para productos a granel
+	UnitPrice  *float64 `json:"unit_price,omitempty" xml:"unit_price,omitempty" yaml:"unit_price,omitempty"`       // para pieza/paquete/lata/six
+	Currency   string   `json:"currency" xml:"currency" yaml:"currency"`
+}
+
+// Scraper sabe extraer un Price de una URL de producto de un retailer concreto.
+type Scraper interface {
+	// Name identifica al retailer, p.ej. "alsuper", "soriana".
+	Name() string
+	// Matches indica si esta URL pertenece al dominio de este scraper.
+	Matches(url string) bool
+	// Fetch descarga y extrae el precio del producto en la URL dada.
+	Fetch(ctx context.Context, url string) (*Price, error)
+}
+
+// -------------------- Registro --------------------
+
+var registry []Scraper
+
+// Register agrega un Scraper al registro global. Los scrapers de este paquete
+// se registran desde el init() de este archivo.
+func Register(s Scraper) {
+	registry = append(registry, s)
+}
+
+// All devuelve todos los scrapers registrados.
+func All() []Scraper {
+	return registry
+}
+
+// For devuelve el scraper que reconoce la URL dada, o nil si ninguno la reclama.
+func For(url string) Scraper {
+	for _, s := range registry {
+		if s.Matches(url) {
+			return s
+		}
+	}
+	return nil
+}
+
+func init() {
+	Register(newAlsuper())
+	Register(newSoriana())
+	Register(newWalmartMX())
+	Register(newBodegaAurrera())
+}
+
+// -------------------- Utilidades compartidas --------------------
+
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+var priceRe = regexp.MustCompile(`\$[\s]*([0-9]+(?:\.[0-9]+)?)`)
+
+// fetchHTML hace un GET simple y devuelve el cuerpo como texto.
+func fetchHTML(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("creando request: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("llamando %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("leyendo body: %w", err)
+	}
+	return string(body), nil
+}
+
+// priceNear busca un precio con formato $123.45 cerca de alguno de los marcadores dados,
+// o en todo el documento si no se encuentra ninguno.
+func priceNear(html string, markers ...string) (float64, error) {
+	segment := html
+	for _, marker := range markers {
+		idx := strings.Index(html, marker)
+		if idx == -1 {
+			continue
+		}
+		start := idx - 200
+		if start < 0 {
+			start = 0
+		}
+		end := idx + 200
+		if end > len(html) {
+			end = len(html)
+		}
+		segment = html[start:end]
+		break
+	}
+
+	m := priceRe.FindStringSubmatch(segment)
+	if m == nil {
+		m = priceRe.FindStringSubmatch(html)
+		if m == nil {
+			return 0, fmt.Errorf("no se encontró un precio con formato $123.45")
+		}
+	}
+
+	raw := strings.ReplaceAll(m[1], ",", "")
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parseando precio %q: %w", raw, err)
+	}
+	return val, nil
+}
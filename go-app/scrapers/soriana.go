@@ -0,0 +1,29 @@
+package scrapers
+
+import (
+	"context"
+	"strings"
+)
+
+// soriana extrae precios del HTML público de soriana.com.
+type soriana struct{}
+
+func newSoriana() *soriana { return &soriana{} }
+
+func (s *soriana) Name() string { return "soriana" }
+
+func (s *soriana) Matches(url string) bool {
+	return strings.Contains(url, "soriana.com")
+}
+
+func (s *soriana) Fetch(ctx context.Context, url string) (*Price, error) {
+	html, err := fetchHTML(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	price, err := priceNear(html, "product-price", "special-price")
+	if err != nil {
+		return nil, err
+	}
+	return &Price{Scraper: s.Name(), URL: url, Currency: "MXN", UnitPrice: &price}, nil
+}
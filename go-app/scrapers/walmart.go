@@ -0,0 +1,29 @@
+package scrapers
+
+import (
+	"context"
+	"strings"
+)
+
+// walmartMX extrae precios del HTML público de walmart.com.mx.
+type walmartMX struct{}
+
+func newWalmartMX() *walmartMX { return &walmartMX{} }
+
+func (w *walmartMX) Name() string { return "walmart_mx" }
+
+func (w *walmartMX) Matches(url string) bool {
+	return strings.Contains(url, "walmart.com.mx")
+}
+
+func (w *walmartMX) Fetch(ctx context.Context, url string) (*Price, error) {
+	html, err := fetchHTML(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	price, err := priceNear(html, "price-main", "current-price")
+	if err != nil {
+		return nil, err
+	}
+	return &Price{Scraper: w.Name(), URL: url, Currency: "MXN", UnitPrice: &price}, nil
+}